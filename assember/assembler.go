@@ -8,8 +8,8 @@ import (
 	"os"
 	"strings"
 
-	"github.com/chrjoh/certificateBar/certificate"
-	"github.com/chrjoh/certificateBar/key"
+	"github.com/ignalina/certificateBar/v2/certificate"
+	"github.com/ignalina/certificateBar/v2/key"
 
 	"gopkg.in/yaml.v2"
 )
@@ -48,11 +48,23 @@ func (c *Certs) setupSigner() {
 
 func (c *Certs) setupKeys() {
 	for _, cert := range c.Certificates {
-		rsaBitsLenght := 2048
-		if cert.CertConfig.KeyLength > 0 {
-			rsaBitsLenght = cert.CertConfig.KeyLength
+		d := cert.CertConfig
+		if keyType, rsaBits, ok := d.certificateKey(); ok {
+			spec := certificate.Certificate{KeyType: keyType, RSABits: rsaBits, AllowWeakKey: d.AllowWeakKey}
+			if err := spec.EnsureKey(); err != nil {
+				log.Fatalf("Failed to generate key for %s: %v", d.Id, err)
+			}
+			cert.PrivateKey = spec.PrivateKey
+			continue
+		}
+		if d.RSABits != 0 {
+			log.Fatalf("Failed to generate key for %s: rsabits is not used with key type %s, use keylength", d.Id, d.KeyType)
+		}
+		privateKey, err := key.Generate(key.KeySpec{Type: d.KeyType, Bits: d.KeyLength, AllowWeak: d.AllowWeakKey})
+		if err != nil {
+			log.Fatalf("Failed to generate key for %s: %v", d.Id, err)
 		}
-		cert.PrivateKey = key.GenerateKey(cert.CertConfig.KeyType, rsaBitsLenght)
+		cert.PrivateKey = privateKey
 	}
 }
 
@@ -68,6 +80,11 @@ func (c *Certs) findByid(id string) (*Cert, error) {
 func (c *Certs) setupTemplates() {
 	for _, cert := range c.Certificates {
 		d := cert.CertConfig
+		keyType, rsaBits, _ := d.certificateKey()
+		serial, err := d.serialNumber()
+		if err != nil {
+			log.Fatalf("Failed to create serial number for %s: %v", d.Id, err)
+		}
 		template := certificate.Certificate{
 			Id:                 d.Id,
 			Country:            d.Pkix.Country,
@@ -77,12 +94,21 @@ func (c *Certs) setupTemplates() {
 			AlternativeNames:   d.AltNames,
 			CA:                 d.CA,
 			PrivateKey:         cert.PrivateKey,
+			KeyType:            keyType,
+			RSABits:            rsaBits,
 			SignatureAlg:       d.HashAlg,
+			AllowInsecureSHA1:  d.SHA1,
+			AllowWeakKey:       d.AllowWeakKey,
 			ValidFrom:          d.ValidFrom(),
 			ValidTo:            d.ValidTo(),
 			Usage:              d.Usage,
+			SerialNumber:       serial,
+		}
+		certTemplate, err := certificate.NewCertificateTemplate(template)
+		if err != nil {
+			log.Fatalf("Failed to create template for %s: %v", d.Id, err)
 		}
-		cert.CertTemplate = certificate.CreateCertificateTemplate(template)
+		cert.CertTemplate = certTemplate
 	}
 }
 
@@ -113,8 +139,16 @@ func (c *Certs) signAll() {
 func (c Certs) Output() {
 	for _, cert := range c.Certificates {
 		if cert.signed {
-			certificate.WritePemToFile(cert.CertBytes, cert.CertConfig.Id+"_crt.pem")
-			key.WritePrivateKeyToPemFile(cert.PrivateKey, cert.CertConfig.Id+"_key.pem")
+			fileName := cert.CertConfig.Id + "_crt.pem"
+			if err := certificate.WritePemToFile(cert.CertBytes, fileName); err != nil {
+				log.Fatalf("error: %v", err)
+			}
+			fmt.Printf("wrote certificate %s to file\n", fileName)
+			keyFileName := cert.CertConfig.Id + "_key.pem"
+			if err := key.WritePrivateKeyToPemFile(cert.PrivateKey, keyFileName); err != nil {
+				log.Fatalf("error: %v", err)
+			}
+			fmt.Printf("wrote private key %s to file\n", keyFileName)
 		}
 		if len(cert.Signers) > 0 {
 			fmt.Printf("Certificate: %s, has certificate chain: %v\n", cert.CertConfig.Id, strings.Join(cert.Signers, ", "))
@@ -139,7 +173,7 @@ func findSigners(c *Certs) []*Cert {
 func readFile(name string) []byte {
 	data, err := ioutil.ReadFile(name)
 	if err != nil {
-		log.Println("Could not read file: %s\n", name)
+		log.Printf("Could not read file: %s\n", name)
 		os.Exit(1)
 	}
 	return data
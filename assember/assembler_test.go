@@ -6,7 +6,7 @@ import (
 	"reflect"
 	"testing"
 
-	"github.com/chrjoh/certificateBar/certificate"
+	"github.com/ignalina/certificateBar/v2/certificate"
 
 	"gopkg.in/yaml.v2"
 )
@@ -15,16 +15,16 @@ func TestReadConfigFile(t *testing.T) {
 	test := marshalCertData("_fixtures/one_cert.yaml", t)
 	c := test.Certificates[0].CertConfig
 	if !c.CA {
-		t.Fatalf("wanted: true, got:", c.CA)
+		t.Fatalf("wanted: true, got: %v", c.CA)
 	}
 	if c.KeyType != "P224" {
-		t.Fatalf("wanted: P224, got:", c.KeyType)
+		t.Fatalf("wanted: P224, got: %v", c.KeyType)
 	}
 	if c.Pkix.CommonName != "www.foo.se" {
-		t.Fatalf("wanted: www.foo.se, got:", c.Pkix.CommonName)
+		t.Fatalf("wanted: www.foo.se, got: %v", c.Pkix.CommonName)
 	}
 	if c.ValidFrom().String() != "2015-11-01 00:00:00 +0000 UTC" {
-		t.Fatalf("wanted: 2015-11-01 00:00:00 +0000 UTC, got:", c.ValidFrom())
+		t.Fatalf("wanted: 2015-11-01 00:00:00 +0000 UTC, got: %v", c.ValidFrom())
 	}
 }
 
@@ -80,6 +80,8 @@ func TestSignAll(t *testing.T) {
 }
 
 func TestCreateInvalidChain(t *testing.T) {
+	defer func(skip bool) { certificate.UnsafeSkipSignerChecks = skip }(certificate.UnsafeSkipSignerChecks)
+	certificate.UnsafeSkipSignerChecks = true
 	test := marshalCertData("_fixtures/illegal_chain.yaml", t)
 	test.setupKeys()
 	test.setupTemplates()
@@ -119,6 +121,55 @@ func TestTemplateSetup(t *testing.T) {
 	}
 }
 
+func TestCertificateKeyType(t *testing.T) {
+	config := `
+certificates:
+  - certificate:
+      id: ec
+      keytype: ec384
+  - certificate:
+      id: rsa
+      keytype: rsa
+      rsabits: 1024
+      allowweakkey: true
+`
+	test := Certs{}
+	if err := yaml.Unmarshal([]byte(config), &test); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	test.setupKeys()
+	test.setupTemplates()
+	ec, _ := test.findByid("ec")
+	if k, ok := ec.PrivateKey.(*ecdsa.PrivateKey); !ok || k.Curve.Params().BitSize != 384 {
+		t.Fatalf("got %T, want a P-384 key", ec.PrivateKey)
+	}
+	rsaCert, _ := test.findByid("rsa")
+	if k, ok := rsaCert.PrivateKey.(*rsa.PrivateKey); !ok || k.N.BitLen() != 1024 {
+		t.Fatalf("got %T, want a 1024 bit RSA key", rsaCert.PrivateKey)
+	}
+	if _, _, ok := (&CertData{KeyType: "P224"}).certificateKey(); ok {
+		t.Fatal("P224 should be generated by key.Generate")
+	}
+}
+
+func TestSerialNumber(t *testing.T) {
+	for id, want := range map[string]int64{"1234": 1234, "0x1A": 26} {
+		test := Certs{Certificates: []*Cert{{CertConfig: CertData{Id: id, KeyType: "P256"}}}}
+		test.setupKeys()
+		test.setupTemplates()
+		if got := test.Certificates[0].CertTemplate.SerialNumber; got.Int64() != want {
+			t.Fatalf("%s: got serial %v, want %d", id, got, want)
+		}
+	}
+	// too long for a serial taken from its bytes
+	test := Certs{Certificates: []*Cert{{CertConfig: CertData{Id: "frontend-server-production-01", KeyType: "P256"}}}}
+	test.setupKeys()
+	test.setupTemplates()
+	if got := test.Certificates[0].CertTemplate.SerialNumber; got.Sign() <= 0 || got.BitLen() > 159 {
+		t.Fatalf("got serial %v, want a random serial", got)
+	}
+}
+
 func TestFindById(t *testing.T) {
 	test := marshalCertData("_fixtures/data.yaml", t)
 	cert, _ := test.findByid("client")
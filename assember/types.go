@@ -2,7 +2,11 @@ package assembler
 
 import (
 	"crypto/x509"
+	"math/big"
+	"strings"
 	"time"
+
+	"github.com/ignalina/certificateBar/v2/certificate"
 )
 
 type PkixData struct {
@@ -13,17 +17,20 @@ type PkixData struct {
 }
 
 type CertData struct {
-	Id        string   `yaml:"id"`
-	CA        bool     `yaml:"ca"`
-	Parent    string   `yaml:"parent"`
-	KeyType   string   `yaml:"keytype"`
-	KeyLength int      `yaml:"keylength"`
-	HashAlg   string   `yaml:"hashalg"`
-	AltNames  []string `yaml:"altnames"`
-	DateFrom  string   `yaml:"validfrom"`
-	DateTo    string   `yaml:"validto"`
-	Pkix      PkixData `yaml:"pkix"`
-	Usage     []string `yaml:"usage"`
+	Id           string   `yaml:"id"`
+	CA           bool     `yaml:"ca"`
+	Parent       string   `yaml:"parent"`
+	KeyType      string   `yaml:"keytype"`
+	KeyLength    int      `yaml:"keylength"`
+	RSABits      int      `yaml:"rsabits"`
+	AllowWeakKey bool     `yaml:"allowweakkey"`
+	HashAlg      string   `yaml:"hashalg"`
+	SHA1         bool     `yaml:"allowinsecuresha1"`
+	AltNames     []string `yaml:"altnames"`
+	DateFrom     string   `yaml:"validfrom"`
+	DateTo       string   `yaml:"validto"`
+	Pkix         PkixData `yaml:"pkix"`
+	Usage        []string `yaml:"usage"`
 }
 
 type Cert struct {
@@ -41,6 +48,28 @@ type Certs struct {
 	certSigners  map[string][]string
 }
 
+// serialNumber returns nil for a numeric id, decimal or hex prefixed with 0x, which
+// NewCertificateTemplate uses as serial number, and a random serial for other ids.
+func (cd *CertData) serialNumber() (*big.Int, error) {
+	digits, base := cd.Id, 10
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		digits, base = digits[2:], 16
+	}
+	if _, ok := new(big.Int).SetString(digits, base); ok {
+		return nil, nil
+	}
+	return certificate.RandomSerials{}.Next()
+}
+
+// certificateKey returns the KeyType and RSABits passed on to certificate.Certificate,
+// ok is false for the key types of key.Generate (RSA, P224, ...).
+func (cd *CertData) certificateKey() (keyType string, rsaBits int, ok bool) {
+	if certificate.IsKeyType(cd.KeyType) || cd.KeyType == "" && cd.RSABits != 0 {
+		return cd.KeyType, cd.RSABits, true
+	}
+	return "", 0, false
+}
+
 func (cd *CertData) ValidFrom() time.Time {
 	if cd.DateFrom == "" {
 		return time.Now()
@@ -0,0 +1,99 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+// BootstrapPKI creates a root CA, an intermediate CA signed by the root and a leaf
+// signed by the intermediate, and writes them with their keys to dir as ca.pem,
+// ca.key, intermediate.pem, intermediate.key, leaf.pem and leaf.key. Specs without
+// a private key get a new key of their KeyType and RSABits, ECDSA P-256 if neither is
+// set, so each level can use its own key. The root and intermediate are always CAs.
+// Existing files are never overwritten, and on error the files written so far are removed.
+func BootstrapPKI(dir string, rootSpec, interSpec, leafSpec Certificate) (err error) {
+	for _, spec := range []*Certificate{&rootSpec, &interSpec, &leafSpec} {
+		if spec.PrivateKey == nil && spec.KeyType == "" && spec.RSABits == 0 {
+			spec.KeyType = "ec256"
+		}
+		if err := spec.EnsureKey(); err != nil {
+			return err
+		}
+	}
+	rootSpec.CA = true
+	interSpec.CA = true
+
+	root, err := NewCA(rootSpec)
+	if err != nil {
+		return fmt.Errorf("failed to create root CA: %v", err)
+	}
+	interDER, err := root.Issue(interSpec)
+	if err != nil {
+		return fmt.Errorf("failed to issue intermediate CA: %v", err)
+	}
+	interCert, err := x509.ParseCertificate(interDER)
+	if err != nil {
+		return err
+	}
+	leafDER, err := NewIssuer(interCert, interSpec.PrivateKey).Issue(leafSpec)
+	if err != nil {
+		return fmt.Errorf("failed to issue leaf: %v", err)
+	}
+
+	var written []string
+	defer func() {
+		if err != nil {
+			for _, f := range written {
+				os.Remove(f)
+			}
+		}
+	}()
+	outputs := []struct {
+		name string
+		der  []byte
+		priv interface{}
+	}{
+		{"ca", root.Cert.Raw, rootSpec.PrivateKey},
+		{"intermediate", interDER, interSpec.PrivateKey},
+		{"leaf", leafDER, leafSpec.PrivateKey},
+	}
+	for _, out := range outputs {
+		keyPem, err := key.EncodePrivateKeyPem(out.priv)
+		if err != nil {
+			return err
+		}
+		certFile := filepath.Join(dir, out.name+".pem")
+		if err := writeNewFile(certFile, encodeCertificatePem(out.der), 0644); err != nil {
+			return err
+		}
+		written = append(written, certFile)
+		keyFile := filepath.Join(dir, out.name+".key")
+		if err := writeNewFile(keyFile, keyPem, 0600); err != nil {
+			return err
+		}
+		written = append(written, keyFile)
+	}
+	return nil
+}
+
+// writeNewFile writes data to name, failing if the file already exists.
+func writeNewFile(name string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(name)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(name)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package certificate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readPemAsDER(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	cert, err := parseCertificatePem(data)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", name, err)
+	}
+	return cert.Raw
+}
+
+func TestBootstrapPKI(t *testing.T) {
+	dir := t.TempDir()
+	err := BootstrapPKI(dir,
+		Certificate{CommonName: "root", OrganizationalUnit: "WebCA"},
+		Certificate{CommonName: "intermediate", OrganizationalUnit: "WebCA"},
+		Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	caBytes := readPemAsDER(t, filepath.Join(dir, "ca.pem"))
+	interBytes := readPemAsDER(t, filepath.Join(dir, "intermediate.pem"))
+	leafBytes := readPemAsDER(t, filepath.Join(dir, "leaf.pem"))
+	if _, err := verifyChain("www.foo.se", caBytes, interBytes, leafBytes, nil); err != nil {
+		t.Fatalf("bootstrapped chain does not verify: %v", err)
+	}
+	if match, err := KeyMatchesCertificateFiles(filepath.Join(dir, "leaf.pem"), filepath.Join(dir, "leaf.key")); !match {
+		t.Fatalf("leaf key does not match: %v", err)
+	}
+}
+
+func TestBootstrapPKICleansUpOnError(t *testing.T) {
+	dir := t.TempDir()
+	// an existing leaf.pem makes the bootstrap fail after ca and intermediate are written
+	existing := filepath.Join(dir, "leaf.pem")
+	os.WriteFile(existing, []byte("keep"), 0644)
+	err := BootstrapPKI(dir, Certificate{CommonName: "root"}, Certificate{CommonName: "intermediate"}, Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}})
+	if !errors.Is(err, os.ErrExist) {
+		t.Fatalf("got: %v, want an error for the existing leaf.pem", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != "leaf.pem" {
+		t.Fatalf("got: %v, want only the existing leaf.pem", entries)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "keep" {
+		t.Fatal("existing file was overwritten")
+	}
+}
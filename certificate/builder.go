@@ -0,0 +1,236 @@
+package certificate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+// Option configures a Certificate created with New. An option returns an
+// error if its input is invalid.
+type Option func(*Certificate) error
+
+// New creates a Certificate for the given common name. Without options the
+// certificate is a leaf valid from now and one year ahead, the same defaults
+// as the config file uses. Contradicting options are reported as an error
+// instead of producing a template that fails later in Sign.
+func New(cn string, opts ...Option) (Certificate, error) {
+	now := time.Now()
+	data := Certificate{
+		CommonName: cn,
+		ValidFrom:  now,
+		ValidTo:    now.AddDate(1, 0, 0),
+	}
+	for _, opt := range opts {
+		if err := opt(&data); err != nil {
+			return Certificate{}, err
+		}
+	}
+	if err := validateCertificate(data); err != nil {
+		return Certificate{}, err
+	}
+	if err := data.EnsureKey(); err != nil {
+		return Certificate{}, err
+	}
+	return data, nil
+}
+
+// WithId sets the id of the certificate.
+func WithId(id string) Option {
+	return func(c *Certificate) error {
+		if id == "" {
+			return errors.New("id must not be empty")
+		}
+		c.Id = id
+		return nil
+	}
+}
+
+// WithCountry sets the country code of the subject.
+func WithCountry(country string) Option {
+	return func(c *Certificate) error {
+		if len(country) != 2 {
+			return fmt.Errorf("country code must be two letters, got: %q", country)
+		}
+		c.Country = country
+		return nil
+	}
+}
+
+// WithOrganization sets the organization and, optionally, the organization unit of the subject.
+func WithOrganization(organization string, unit ...string) Option {
+	return func(c *Certificate) error {
+		if organization == "" {
+			return errors.New("organization must not be empty")
+		}
+		if len(unit) > 1 {
+			return fmt.Errorf("only one organization unit is supported, got: %v", unit)
+		}
+		c.Organization = organization
+		if len(unit) == 1 {
+			c.OrganizationalUnit = unit[0]
+		}
+		return nil
+	}
+}
+
+// WithSANs adds alternative DNS names to the certificate.
+func WithSANs(names ...string) Option {
+	return func(c *Certificate) error {
+		for _, name := range names {
+			if name == "" {
+				return errors.New("alternative name must not be empty")
+			}
+		}
+		c.AlternativeNames = append(c.AlternativeNames, names...)
+		return nil
+	}
+}
+
+// WithIssuerURL adds a URL where the issuing certificate is published.
+func WithIssuerURL(url string) Option {
+	return func(c *Certificate) error {
+		if url == "" {
+			return errors.New("issuer URL must not be empty")
+		}
+		c.IssuerURLs = append(c.IssuerURLs, url)
+		return nil
+	}
+}
+
+// SetChainIssuerURLs points every certificate in chain to the published URL of its
+// issuer. chain is ordered from the leaf to the root and urls[i] is where chain[i]
+// is published, the root itself gets no issuer URL.
+func SetChainIssuerURLs(chain []Certificate, urls []string) error {
+	if len(chain) != len(urls) {
+		return fmt.Errorf("got %d urls for a chain of %d certificates", len(urls), len(chain))
+	}
+	for i := 0; i < len(chain)-1; i++ {
+		if urls[i+1] == "" {
+			return fmt.Errorf("no URL for the issuer of %s", chain[i].CommonName)
+		}
+		chain[i].IssuerURLs = []string{urls[i+1]}
+	}
+	return nil
+}
+
+// WithValidity sets the certificate to be valid for d counted from ValidFrom.
+func WithValidity(d time.Duration) Option {
+	return func(c *Certificate) error {
+		if d <= 0 {
+			return fmt.Errorf("validity must be positive, got: %v", d)
+		}
+		c.ValidTo = c.ValidFrom.Add(d)
+		return nil
+	}
+}
+
+// WithValidityPeriod sets explicit start and end times of the certificate.
+func WithValidityPeriod(from, to time.Time) Option {
+	return func(c *Certificate) error {
+		c.ValidFrom = from
+		c.ValidTo = to
+		return nil
+	}
+}
+
+// WithCA marks the certificate as a CA. pathLen limits the number of
+// intermediate CAs that may follow it in a chain, -1 means no limit.
+func WithCA(pathLen int) Option {
+	return func(c *Certificate) error {
+		if pathLen < -1 {
+			return fmt.Errorf("path length must be -1 or larger, got: %d", pathLen)
+		}
+		c.CA = true
+		c.MaxPathLen = pathLen
+		c.MaxPathLenZero = pathLen == 0
+		return nil
+	}
+}
+
+// WithUsage sets the key usage, see getUsage for the valid values.
+func WithUsage(usage ...string) Option {
+	return func(c *Certificate) error {
+		for _, u := range usage {
+			if !isKnownUsage(u) {
+				return fmt.Errorf("unknown usage: %q", u)
+			}
+		}
+		c.Usage = usage
+		return nil
+	}
+}
+
+// WithSignatureAlg sets the hash used for the signature, SHA1, SHA256, SHA384, SHA512
+// or auto to pick the hash matching the strength of the key.
+func WithSignatureAlg(alg string) Option {
+	return func(c *Certificate) error {
+		switch alg {
+		case "SHA1", "SHA256", "SHA384", "SHA512", "auto":
+			c.SignatureAlg = alg
+			return nil
+		}
+		return fmt.Errorf("unknown signature algorithm: %q", alg)
+	}
+}
+
+// WithInsecureSHA1 permits the SHA1 signature algorithm, only use it for legacy devices.
+func WithInsecureSHA1() Option {
+	return func(c *Certificate) error {
+		c.AllowInsecureSHA1 = true
+		return nil
+	}
+}
+
+// WithRSABits makes New generate an RSA key of the given length, at least
+// key.MinRSAKeyBits.
+func WithRSABits(bits int) Option {
+	return func(c *Certificate) error {
+		if bits < key.MinRSAKeyBits {
+			return fmt.Errorf("RSA key length %d is shorter than %d bits", bits, key.MinRSAKeyBits)
+		}
+		c.RSABits = bits
+		return nil
+	}
+}
+
+// WithPrivateKey sets the private key of the certificate.
+func WithPrivateKey(privateKey interface{}) Option {
+	return func(c *Certificate) error {
+		if privateKey == nil {
+			return errors.New("private key must not be nil")
+		}
+		c.PrivateKey = privateKey
+		return nil
+	}
+}
+
+// WithKeyType declares the key type of the certificate, see Certificate.KeyType. New
+// generates a key of the type unless WithPrivateKey is given as well.
+func WithKeyType(keyType string) Option {
+	return func(c *Certificate) error {
+		if !isStringInList(keyType, keyTypes) {
+			return fmt.Errorf("unknown key type: %q", keyType)
+		}
+		c.KeyType = keyType
+		return nil
+	}
+}
+
+func validateCertificate(data Certificate) error {
+	if data.ValidFrom.IsZero() || data.ValidTo.IsZero() {
+		return errors.New("both ValidFrom and ValidTo must be set")
+	}
+	if !data.ValidTo.After(data.ValidFrom) {
+		return fmt.Errorf("ValidTo %v is not after ValidFrom %v", data.ValidTo, data.ValidFrom)
+	}
+	if err := checkSHA1Allowed(data); err != nil {
+		return err
+	}
+	if data.CA && len(data.Usage) > 0 && !isStringInList("certsign", data.Usage) {
+		return fmt.Errorf("CA certificate must have certsign usage, got: %v", data.Usage)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestNewDefaults(t *testing.T) {
+	c, err := New("www.foo.se")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.CommonName != "www.foo.se" {
+		t.Fatalf("got: %v, want www.foo.se", c.CommonName)
+	}
+	if c.CA {
+		t.Fatal("certificate should not be a CA by default")
+	}
+	if !c.ValidTo.After(c.ValidFrom) {
+		t.Fatalf("ValidTo %v not after ValidFrom %v", c.ValidTo, c.ValidFrom)
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	c, err := New("www.foo.se",
+		WithId("one"),
+		WithCountry("SE"),
+		WithOrganization("test", "WebCA"),
+		WithSANs("www.bar.se"),
+		WithValidity(24*time.Hour),
+		WithCA(1),
+		WithUsage("certsign", "crlsign"),
+		WithPrivateKey(key.GenerateKey("P256", 0)),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.OrganizationalUnit != "WebCA" || !c.CA || c.MaxPathLen != 1 {
+		t.Fatalf("options not applied: %+v", c)
+	}
+	if c.ValidTo.Sub(c.ValidFrom) != 24*time.Hour {
+		t.Fatalf("got: %v, want 24h", c.ValidTo.Sub(c.ValidFrom))
+	}
+	template := mustCreateTemplate(t, c)
+	if !template.IsCA || template.MaxPathLen != 1 {
+		t.Fatalf("got: IsCA %v MaxPathLen %v, want true 1", template.IsCA, template.MaxPathLen)
+	}
+	if template.KeyUsage != x509.KeyUsageCertSign|x509.KeyUsageCRLSign {
+		t.Fatalf("got: %v, want certsign and crlsign", template.KeyUsage)
+	}
+}
+
+func TestNewWithCAPathLenZero(t *testing.T) {
+	c, err := New("www.foo.se", WithCA(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !c.MaxPathLenZero {
+		t.Fatal("MaxPathLenZero should be set for path length 0")
+	}
+}
+
+func TestNewInvalidOptions(t *testing.T) {
+	now := time.Now()
+	tests := map[string][]Option{
+		"negative validity":  {WithValidity(-time.Hour)},
+		"reversed validity":  {WithValidityPeriod(now, now.Add(-time.Hour))},
+		"unknown usage":      {WithUsage("foo")},
+		"ca with serverauth": {WithCA(-1), WithUsage("serverauth")},
+		"empty san":          {WithSANs("")},
+		"bad country":        {WithCountry("SWE")},
+		"bad path length":    {WithCA(-2)},
+		"bad hash":           {WithSignatureAlg("MD5")},
+	}
+	for name, opts := range tests {
+		if _, err := New("www.foo.se", opts...); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestNewInteroperatesWithSign(t *testing.T) {
+	caPriv := key.GenerateKey("P256", 0)
+	caData, err := New("ca", WithId("one"), WithCA(-1), WithPrivateKey(caPriv))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ca := mustCreateTemplate(t, caData)
+	caBytes := Sign(ca, ca, key.PublicKey(caPriv), caPriv)
+
+	clientPriv := key.GenerateKey("P256", 0)
+	clientData, err := New("www.baz.se", WithId("two"), WithSANs("www.bar.se"), WithPrivateKey(clientPriv))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := mustCreateTemplate(t, clientData)
+	clientBytes := Sign(client, ca, key.PublicKey(clientPriv), caPriv)
+	if !CheckCertificate("www.bar.se", caBytes, nil, clientBytes) {
+		t.Fatal("certificate built with New did not verify")
+	}
+}
+
+func TestNewWithIssuerURL(t *testing.T) {
+	issuer := testIssuer(t)
+	data, err := New("www.foo.se", WithSANs("www.foo.se"), WithIssuerURL("http://pki.foo.se/ca.crt"), WithPrivateKey(key.GenerateKey("P256", 0)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	der, err := issuer.Issue(data)
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	if len(cert.IssuingCertificateURL) != 1 || cert.IssuingCertificateURL[0] != "http://pki.foo.se/ca.crt" {
+		t.Fatalf("got: %v, want http://pki.foo.se/ca.crt", cert.IssuingCertificateURL)
+	}
+}
+
+func TestSetChainIssuerURLs(t *testing.T) {
+	chain := []Certificate{{CommonName: "leaf"}, {CommonName: "inter"}, {CommonName: "root"}}
+	urls := []string{"", "http://pki.foo.se/inter.crt", "http://pki.foo.se/root.crt"}
+	if err := SetChainIssuerURLs(chain, urls); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if chain[0].IssuerURLs[0] != urls[1] || chain[1].IssuerURLs[0] != urls[2] || chain[2].IssuerURLs != nil {
+		t.Fatalf("unexpected issuer URLs: %+v", chain)
+	}
+	if err := SetChainIssuerURLs(chain, urls[1:]); err == nil {
+		t.Fatal("expected error for mismatched lengths")
+	}
+}
+
+func TestSHA1RequiresOptIn(t *testing.T) {
+	if _, err := New("www.foo.se", WithSignatureAlg("SHA1")); err == nil || !strings.Contains(err.Error(), "AllowInsecureSHA1") {
+		t.Fatalf("got: %v, want error naming AllowInsecureSHA1", err)
+	}
+	data, err := New("www.foo.se", WithSignatureAlg("SHA1"), WithInsecureSHA1(), WithPrivateKey(key.GenerateKey("RSA", 1024)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data.AllowWeakKey = true
+	if template := mustCreateTemplate(t, data); template.SignatureAlgorithm != x509.SHA1WithRSA {
+		t.Fatalf("got: %v, want SHA1WithRSA", template.SignatureAlgorithm)
+	}
+	data.AllowInsecureSHA1 = false
+	if _, err := NewCertificateTemplate(data); err == nil {
+		t.Fatal("expected error for SHA1 without AllowInsecureSHA1")
+	}
+}
+
+func TestNewWithKeyType(t *testing.T) {
+	data, err := New("www.foo.se", WithKeyType("ec384"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if k, ok := data.PrivateKey.(*ecdsa.PrivateKey); !ok || k.Curve != elliptic.P384() {
+		t.Fatalf("got key %T, want P-384", data.PrivateKey)
+	}
+	if _, err := New("www.foo.se", WithKeyType("ec384"), WithPrivateKey(key.GenerateKey("P256", 0))); err == nil {
+		t.Fatal("expected error for a key of another type")
+	}
+	if _, err := New("www.foo.se", WithKeyType("dsa")); err == nil {
+		t.Fatal("expected error for an unknown key type")
+	}
+}
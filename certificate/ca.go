@@ -0,0 +1,145 @@
+package certificate
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+var (
+	// ErrLoadCACert is returned then the CA certificate file can not be read or parsed.
+	ErrLoadCACert = errors.New("failed to load CA certificate")
+	// ErrLoadCAKey is returned then the CA key file can not be read or parsed, or
+	// does not belong to the CA certificate.
+	ErrLoadCAKey = errors.New("failed to load CA key")
+)
+
+// CA is a self signed certificate authority kept in memory that issues leaf
+// certificates with unique serial numbers. It is safe for concurrent use.
+type CA struct {
+	Cert   *x509.Certificate
+	Key    interface{}
+	mu     sync.Mutex
+	issued map[string]bool
+}
+
+// NewCA creates a self signed CA from data, which must have CA set and a private key
+// or a KeyType to generate one, the key is kept in the Key of the CA.
+func NewCA(data Certificate) (*CA, error) {
+	if !data.CA {
+		return nil, errors.New("certificate is not a CA")
+	}
+	if err := data.EnsureKey(); err != nil {
+		return nil, err
+	}
+	if data.PrivateKey == nil {
+		return nil, errors.New("CA has no private key")
+	}
+	template, err := NewCertificateTemplate(data)
+	if err != nil {
+		return nil, err
+	}
+	der, err := SignCertificate(template, template, key.PublicKey(data.PrivateKey), data.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{Cert: cert, Key: data.PrivateKey, issued: map[string]bool{}}, nil
+}
+
+// Issue signs a leaf certificate with the CA. The serial number is assigned by the
+// CA and is unique among the certificates it has issued.
+func (ca *CA) Issue(leaf Certificate) ([]byte, error) {
+	serial, err := ca.reserveSerial()
+	if err != nil {
+		return nil, err
+	}
+	der, err := issueCertificate(leaf, serial, ca.Cert, ca.Key)
+	if err != nil {
+		ca.mu.Lock()
+		delete(ca.issued, serial.String())
+		ca.mu.Unlock()
+		return nil, err
+	}
+	return der, nil
+}
+
+// reserveSerial returns a random serial that has not been issued before.
+func (ca *CA) reserveSerial() (*big.Int, error) {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	if ca.issued == nil {
+		ca.issued = map[string]bool{}
+	}
+	for {
+		serial, err := randomSerial()
+		if err != nil {
+			return nil, err
+		}
+		if !ca.issued[serial.String()] {
+			ca.issued[serial.String()] = true
+			return serial, nil
+		}
+	}
+}
+
+// IssueLeafFromFiles issues leaf with the PEM encoded CA certificate and private key
+// in caCertPath and caKeyPath and returns the DER encoded certificate. Load errors
+// wrap ErrLoadCACert or ErrLoadCAKey.
+func IssueLeafFromFiles(caCertPath, caKeyPath string, leaf Certificate) ([]byte, error) {
+	certPem, err := os.ReadFile(caCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLoadCACert, err)
+	}
+	caCert, err := parseCertificatePem(certPem)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrLoadCACert, caCertPath, err)
+	}
+	keyPem, err := os.ReadFile(caKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLoadCAKey, err)
+	}
+	caKey, err := key.ParsePrivateKeyPem(keyPem)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrLoadCAKey, caKeyPath, err)
+	}
+	if err := CheckKeyMatchesCertificate(caCert, caKey); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrLoadCAKey, caKeyPath, err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	return issueCertificate(leaf, serial, caCert, caKey)
+}
+
+// IssueBatchContext issues a certificate for every spec with the CA certificate and key,
+// in order. ctx is checked before each spec, on cancellation or on an issuance error the
+// certificates issued so far are returned together with the error.
+func IssueBatchContext(ctx context.Context, specs []Certificate, ca *x509.Certificate, caKey interface{}) ([][]byte, error) {
+	var issued [][]byte
+	for i, spec := range specs {
+		if err := ctx.Err(); err != nil {
+			return issued, fmt.Errorf("batch stopped after %d of %d certificates: %w", len(issued), len(specs), err)
+		}
+		serial, err := randomSerial()
+		if err != nil {
+			return issued, err
+		}
+		der, err := issueCertificate(spec, serial, ca, caKey)
+		if err != nil {
+			return issued, fmt.Errorf("failed to issue certificate %d (%s): %v", i, spec.CommonName, err)
+		}
+		issued = append(issued, der)
+	}
+	return issued, nil
+}
@@ -0,0 +1,132 @@
+package certificate
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestCAIssue(t *testing.T) {
+	ca, err := NewCA(Certificate{Id: "ca", OrganizationalUnit: "WebCA", CA: true, PrivateKey: key.GenerateKey("P256", 0)})
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	serials := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("www%d.foo.se", i)
+		der, err := ca.Issue(Certificate{CommonName: name, AlternativeNames: []string{name}, PrivateKey: key.GenerateKey("P256", 0)})
+		if err != nil {
+			t.Fatalf("failed to issue certificate: %v", err)
+		}
+		cert, _ := x509.ParseCertificate(der)
+		if serials[cert.SerialNumber.String()] {
+			t.Fatalf("serial %v issued twice", cert.SerialNumber)
+		}
+		serials[cert.SerialNumber.String()] = true
+		if !CheckCertificate(name, ca.Cert.Raw, nil, der) {
+			t.Fatalf("certificate %s failed to verify", name)
+		}
+	}
+	if len(ca.issued) != 10 {
+		t.Fatalf("got %d recorded serials, want 10", len(ca.issued))
+	}
+}
+
+func TestNewCAInvalid(t *testing.T) {
+	if _, err := NewCA(Certificate{Id: "ca", PrivateKey: key.GenerateKey("P256", 0)}); err == nil {
+		t.Fatal("expected error for non CA certificate")
+	}
+	if _, err := NewCA(Certificate{Id: "ca", CA: true}); err == nil {
+		t.Fatal("expected error for CA without key")
+	}
+}
+
+func TestIssueLeafFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := BootstrapPKI(dir, Certificate{CommonName: "root"}, Certificate{CommonName: "intermediate"}, Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}}); err != nil {
+		t.Fatalf("failed to bootstrap: %v", err)
+	}
+	caCert, caKey := filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca.key")
+	leaf := Certificate{CommonName: "www.bar.se", AlternativeNames: []string{"www.bar.se"}, PrivateKey: key.GenerateKey("P256", 0)}
+	der, err := IssueLeafFromFiles(caCert, caKey, leaf)
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	if _, err := verifyChain("www.bar.se", readPemAsDER(t, caCert), nil, der, nil); err != nil {
+		t.Fatalf("issued certificate does not verify: %v", err)
+	}
+	if _, err := IssueLeafFromFiles(filepath.Join(dir, "missing.pem"), caKey, leaf); !errors.Is(err, ErrLoadCACert) {
+		t.Fatalf("got: %v, want ErrLoadCACert", err)
+	}
+	if _, err := IssueLeafFromFiles(caCert, caCert, leaf); !errors.Is(err, ErrLoadCAKey) {
+		t.Fatalf("got: %v, want ErrLoadCAKey", err)
+	}
+	if _, err := IssueLeafFromFiles(caCert, filepath.Join(dir, "leaf.key"), leaf); !errors.Is(err, ErrLoadCAKey) {
+		t.Fatalf("got: %v, want ErrLoadCAKey for a key of another certificate", err)
+	}
+}
+
+// cancelAfter is a context that is cancelled after its Err has been asked n times.
+type cancelAfter struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.n == 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func TestIssueBatchContext(t *testing.T) {
+	ca, err := NewCA(Certificate{Id: "ca", OrganizationalUnit: "WebCA", CA: true, PrivateKey: key.GenerateKey("P256", 0)})
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	var specs []Certificate
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprintf("www%d.foo.se", i)
+		specs = append(specs, Certificate{CommonName: name, AlternativeNames: []string{name}, PrivateKey: key.GenerateKey("P256", 0)})
+	}
+	issued, err := IssueBatchContext(context.Background(), specs, ca.Cert, ca.Key)
+	if err != nil || len(issued) != 3 {
+		t.Fatalf("got %d certificates and %v, want 3", len(issued), err)
+	}
+	issued, err = IssueBatchContext(&cancelAfter{Context: context.Background(), n: 1}, specs, ca.Cert, ca.Key)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got: %v, want context.Canceled", err)
+	}
+	if len(issued) != 1 {
+		t.Fatalf("got %d certificates, want only the first", len(issued))
+	}
+}
+
+func TestCAIssueConcurrent(t *testing.T) {
+	ca, err := NewCA(Certificate{Id: "ca", OrganizationalUnit: "WebCA", CA: true, PrivateKey: key.GenerateKey("P256", 0)})
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	leafKey := key.GenerateKey("P256", 0)
+	var wg sync.WaitGroup
+	for g := 0; g < 100; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ca.Issue(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: leafKey}); err != nil {
+				t.Errorf("failed to issue: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(ca.issued) != 100 {
+		t.Fatalf("got %d serials, want 100", len(ca.issued))
+	}
+}
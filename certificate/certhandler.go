@@ -1,20 +1,30 @@
 package certificate
 
 import (
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/pem"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"math/big"
+	"net"
+	"net/mail"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/chrjoh/certificateBar/key"
+	"github.com/ignalina/certificateBar/v2/key"
 )
 
 // view remote certificate
@@ -30,82 +40,639 @@ type Certificate struct {
 	OrganizationalUnit string
 	CommonName         string
 	AlternativeNames   []string
+	IPAddresses        []net.IP
 	Usage              []string
 	CA                 bool
-	PrivateKey         interface{}
-	SignatureAlg       string
-	ValidFrom          time.Time
-	ValidTo            time.Time
+	MaxPathLen         int
+	MaxPathLenZero     bool
+	// OmitBasicConstraints leaves out the basic constraints extension on leaf certificates.
+	OmitBasicConstraints bool
+	PrivateKey           interface{}
+	SignatureAlg         string
+	ValidFrom            time.Time
+	ValidTo              time.Time
+	// ValidFor and NotBeforeSkew are used then ValidFrom is not set, the certificate
+	// is then valid from now minus the skew and ValidFor ahead. ValidFor defaults to
+	// one year, three years for certificates with the codesigning usage. NotBeforeSkew
+	// defaults to five minutes, a negative NotBeforeSkew makes it valid from now.
+	ValidFor      time.Duration
+	NotBeforeSkew time.Duration
+	// SKIDMethod selects how the subject key identifier is computed, SHA-1 by default.
+	SKIDMethod SKIDMethod
+	// SCTList holds serialized signed certificate timestamps from certificate transparency logs.
+	SCTList [][]byte
+	// IssuerURLs are put in the authority information access extension as CA issuers
+	// locations, letting clients fetch the issuing certificate.
+	IssuerURLs []string
+	// AllowInsecureSHA1 permits SignatureAlg SHA1 for legacy devices, modern clients
+	// refuse to verify such certificates.
+	AllowInsecureSHA1 bool
+	// CheckPublicSuffix rejects wildcard names covering a public suffix, such as *.com or *.co.uk.
+	CheckPublicSuffix bool
+	// KeepUnicodeCN keeps an internationalized CommonName as given for display, the DNS
+	// names are always stored in their xn-- A-label form.
+	KeepUnicodeCN bool
+	// KeyType declares the key of the certificate: rsa, rsa2048, rsa4096, ec256, ec384,
+	// ec521 or ed25519. EnsureKey, NewCA and the Issuer generate such a key if PrivateKey
+	// is nil, a provided key of another type is an error.
+	KeyType string
+	// Version 1 creates a certificate without extensions for legacy systems, it can not
+	// have alternative names. The default is version 3.
+	Version int
+	// RSABits is the length of a generated RSA key, 2048 if not set. Setting it without
+	// KeyType declares an RSA key.
+	RSABits int
+	// AllowWeakKey permits RSA keys shorter than key.MinRSAKeyBits, both generated and
+	// provided ones, e.g. to speed up tests. Without it NewCertificateTemplate, and so
+	// NewCA, Issuer and the other issuing functions, refuse such keys.
+	AllowWeakKey bool
+	// QCStatements are put in the qcStatements extension, e.g. for eIDAS qualified certificates.
+	QCStatements []QCStatement
+	// EmailAddresses are added as rfc822Name subject alternative names, as S/MIME
+	// clients require. EmailInSubject also puts the first one in the legacy
+	// emailAddress attribute of the subject.
+	EmailAddresses []string
+	EmailInSubject bool
+	// UPN is the Microsoft user principal name, such as user@ad.foo.se, added as an
+	// otherName subject alternative name for smart card logon.
+	UPN string
+	// ExtraSubjectAttributes are added to the subject after the attributes above.
+	ExtraSubjectAttributes []SubjectAttribute
+	// UnknownExtKeyUsage are extended key usages in dotted form, e.g. 1.3.6.1.4.1.311.20.2.2,
+	// added after the ones from Usage.
+	UnknownExtKeyUsage []string
+	// AllowUnderscores accepts underscores in DNS names, as used by some internal DNS,
+	// they are not valid host names and public CAs reject them.
+	AllowUnderscores bool
+	// SANCritical marks the subject alternative name extension critical, crypto/x509 only
+	// does so when the subject is empty. The key usage extension is always critical.
+	SANCritical bool
+	// DirectoryAttributes are put in the subjectDirectoryAttributes extension, e.g. the
+	// DateOfBirth and PlaceOfBirth of national ID certificates.
+	DirectoryAttributes []DirectoryAttribute
+	// SerialNumber is used instead of the serial derived from Id if set. Issuer, CA and
+	// the other issuing functions assign their own serial and ignore it.
+	SerialNumber *big.Int
 }
 
+// RandReader is the source of randomness for signatures and random serial numbers,
+// Sign and CreateCertificateTemplate included. It defaults to crypto/rand.Reader,
+// tests may set a deterministic reader to get reproducible output. Production code
+// must keep the default, a predictable reader makes serials guessable and can leak
+// ECDSA keys through their signatures.
+var RandReader io.Reader = rand.Reader
+
+// UnsafeSkipSignerChecks disables the check that the signer of a certificate is a CA
+// allowed to sign certificates. Only meant for building deliberately broken fixtures.
+var UnsafeSkipSignerChecks bool
+
+const (
+	defaultNotBeforeSkew = 5 * time.Minute
+	defaultValidFor      = 365 * 24 * time.Hour
+	// codeSigningValidFor is longer as signatures are verified long after signing.
+	codeSigningValidFor = 3 * 365 * 24 * time.Hour
+)
+
+// Sign is SignCertificate for setups where a failure to sign is a programming error,
+// it panics instead of returning the error.
 func Sign(cert *x509.Certificate, signer *x509.Certificate, certPubKey, signerPrivateKey interface{}) []byte {
-	derBytes, err := x509.CreateCertificate(rand.Reader, cert, signer, certPubKey, signerPrivateKey)
+	derBytes, err := SignCertificate(cert, signer, certPubKey, signerPrivateKey)
 	if err != nil {
-		log.Println(err)
-		log.Fatalf("Failed to sign cetificate: %v\n", cert.Subject)
+		panic(fmt.Sprintf("failed to sign certificate %v: %v", cert.Subject, err))
 	}
 	return derBytes
 }
 
+// SignCertificate signs cert with the signer certificate and private key and returns
+// the DER encoded certificate. A self-signed certificate passes itself as signer.
+func SignCertificate(cert *x509.Certificate, signer *x509.Certificate, certPubKey, signerPrivateKey interface{}) ([]byte, error) {
+	if !cert.NotBefore.Before(cert.NotAfter) {
+		return nil, fmt.Errorf("certificate %v has an empty validity period, NotBefore %v is not before NotAfter %v", cert.Subject, cert.NotBefore, cert.NotAfter)
+	}
+	if signer != cert && !UnsafeSkipSignerChecks {
+		if err := checkSigner(signer); err != nil {
+			return nil, err
+		}
+	}
+	if cert.Version == 1 {
+		return createV1Certificate(cert, signer, certPubKey, signerPrivateKey)
+	}
+	return x509.CreateCertificate(RandReader, cert, signer, certPubKey, signerPrivateKey)
+}
+
+// checkRSAKeyBits returns an error if pub is an RSA key shorter than minBits.
+func checkRSAKeyBits(pub interface{}, minBits int) error {
+	if k, ok := pub.(*rsa.PublicKey); ok && k.N.BitLen() < minBits {
+		return fmt.Errorf("RSA key is %d bits, want at least %d", k.N.BitLen(), minBits)
+	}
+	return nil
+}
+
+// randomSerial returns a random positive serial number of at most 128 bits.
+func randomSerial() (*big.Int, error) {
+	serial, err := rand.Int(RandReader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	if serial.Sign() == 0 {
+		return randomSerial()
+	}
+	return serial, nil
+}
+
+// serialNumber interprets id as a hex number when prefixed with 0x and as a decimal
+// number otherwise. An empty id gives a random serial and an id that is not a number
+// falls back to the bytes of the id, as ids were used before. A negative number,
+// which is not allowed in X.509, is replaced by its absolute value.
+func serialNumber(id string) (*big.Int, error) {
+	if id == "" {
+		return randomSerial()
+	}
+	var serial *big.Int
+	if strings.HasPrefix(id, "0x") || strings.HasPrefix(id, "0X") {
+		var ok bool
+		if serial, ok = new(big.Int).SetString(id[2:], 16); !ok {
+			return nil, fmt.Errorf("invalid hex serial number: %q", id)
+		}
+	} else if decimal, ok := new(big.Int).SetString(id, 10); ok {
+		serial = decimal
+	} else {
+		serial = new(big.Int).SetBytes([]byte(id))
+	}
+	serial.Abs(serial)
+	if err := checkSerial(serial); err != nil {
+		return nil, fmt.Errorf("invalid serial number from id %q: %v", id, err)
+	}
+	return serial, nil
+}
+
+// checkSerial enforces the RFC 5280 rules for serial numbers: positive and at most
+// 20 octets when DER encoded, which leaves 159 bits for the value.
+func checkSerial(serial *big.Int) error {
+	if serial.Sign() <= 0 {
+		return errors.New("serial number must be positive")
+	}
+	if serial.BitLen() > 159 {
+		return errors.New("serial number is longer than 20 octets")
+	}
+	return nil
+}
+
+// CreateCertificateTemplate is NewCertificateTemplate for setups where invalid data is a
+// programming error, it panics instead of returning the error.
+func CreateCertificateTemplate(data Certificate) *x509.Certificate {
+	cert, err := NewCertificateTemplate(data)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create certificate template %s: %v", data.Id, err))
+	}
+	return cert
+}
+
 // NOTE:
 //If an SSL certificate has a Subject Alternative Name (SAN) field, then SSL clients are supposed to ignore
 //the common name value and seek a match in the SAN list.
 //This is why the Cert always repeats the common name as the first SAN in the certificate.
-func CreateCertificateTemplate(data Certificate) *x509.Certificate {
+//
+// NewCertificateTemplate returns the certificate template for data, or an error if
+// data is invalid. data must have a PrivateKey, EnsureKey generates one of its KeyType.
+func NewCertificateTemplate(data Certificate) (*x509.Certificate, error) {
+	if err := checkSHA1Allowed(data); err != nil {
+		return nil, err
+	}
+	if err := data.checkKey(); err != nil {
+		return nil, err
+	}
+	if err := checkVersion(data); err != nil {
+		return nil, err
+	}
+	if _, err := subjectAttributes(data); err != nil {
+		return nil, err
+	}
+	if data.PrivateKey == nil {
+		return nil, errors.New("certificate has no private key, set PrivateKey or call EnsureKey with a KeyType")
+	}
 	pub := key.PublicKey(data.PrivateKey)
-	subjectKeyId := keyIdentifier(pub)
+	if !data.AllowWeakKey {
+		if err := checkRSAKeyBits(pub, key.MinRSAKeyBits); err != nil {
+			return nil, err
+		}
+	}
+	subjectKeyId, err := keyIdentifier(pub, data.SKIDMethod)
+	if err != nil {
+		return nil, fmt.Errorf("subject key identifier: %v", err)
+	}
 	keyUsage, extKeyUsage := getUsage(data.Usage, data.CA)
+	notBefore, notAfter := buildValidity(data, time.Now())
+	serial := data.SerialNumber
+	if serial == nil {
+		if serial, err = serialNumber(data.Id); err != nil {
+			return nil, err
+		}
+	} else if err := checkSerial(serial); err != nil {
+		return nil, fmt.Errorf("invalid serial number: %v", err)
+	}
 	cert := &x509.Certificate{
-		SerialNumber: new(big.Int).SetBytes([]byte(data.Id)),
-		Subject: pkix.Name{
-			Country:            []string{data.Country},
-			Organization:       []string{data.Organization},
-			OrganizationalUnit: []string{data.OrganizationalUnit},
-		},
-		NotBefore:             data.ValidFrom,
-		NotAfter:              data.ValidTo,
+		SerialNumber:          serial,
+		Subject:               buildSubject(data),
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
 		SubjectKeyId:          subjectKeyId,
-		BasicConstraintsValid: true,
+		BasicConstraintsValid: data.CA || !data.OmitBasicConstraints,
 		SignatureAlgorithm:    signatureAlgorithm(data.SignatureAlg, data.PrivateKey),
 		IsCA:                  data.CA,
 		ExtKeyUsage:           extKeyUsage,
 		KeyUsage:              keyUsage,
 	}
 
-	if data.CommonName != "" {
-		cert.Subject.CommonName = data.CommonName
+	if data.CA {
+		cert.MaxPathLen = data.MaxPathLen
+		cert.MaxPathLenZero = data.MaxPathLenZero
 	}
 
-	//TODO: handle alternative ip
+	cert.IPAddresses = data.IPAddresses
+	cert.IssuingCertificateURL = data.IssuerURLs
+	for _, usage := range data.UnknownExtKeyUsage {
+		oid, err := parseOID(usage)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extended key usage: %v", err)
+		}
+		cert.UnknownExtKeyUsage = append(cert.UnknownExtKeyUsage, oid)
+	}
+	if err := checkEmailAddresses(data.EmailAddresses); err != nil {
+		return nil, err
+	}
+	cert.EmailAddresses = data.EmailAddresses
 
 	if len(data.AlternativeNames) > 0 {
-		cert.DNSNames = data.AlternativeNames
-		if !isStringInList(data.CommonName, data.AlternativeNames) {
-			cert.DNSNames = append(cert.DNSNames, data.CommonName)
+		names, err := toASCIINames(data.AlternativeNames)
+		if err != nil {
+			return nil, err
+		}
+		if data.CommonName != "" {
+			cn, err := toASCIIName(data.CommonName)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, cn)
+			if !data.KeepUnicodeCN {
+				cert.Subject.CommonName = cn
+			}
 		}
+		cert.DNSNames = normalizeDNSNames(names)
 	}
-	return cert
+	if err := validateDNSNames(cert.DNSNames, data.CheckPublicSuffix, data.AllowUnderscores); err != nil {
+		return nil, err
+	}
+
+	if len(data.SCTList) > 0 {
+		ext, err := sctListExtension(data.SCTList)
+		if err != nil {
+			return nil, err
+		}
+		cert.ExtraExtensions = append(cert.ExtraExtensions, ext)
+	}
+	if len(data.QCStatements) > 0 {
+		if err := QCStatements(cert, data.QCStatements...); err != nil {
+			return nil, err
+		}
+	}
+	if len(data.DirectoryAttributes) > 0 {
+		if err := SubjectDirectoryAttributes(cert, data.DirectoryAttributes...); err != nil {
+			return nil, err
+		}
+	}
+	hasSAN := len(cert.DNSNames)+len(cert.EmailAddresses)+len(cert.IPAddresses) > 0
+	if data.UPN != "" || data.SANCritical && hasSAN {
+		ext, err := sanExtension(cert, data.UPN, data.SANCritical)
+		if err != nil {
+			return nil, err
+		}
+		// replaces the extension crypto/x509 would write from the names
+		cert.ExtraExtensions = append(cert.ExtraExtensions, ext)
+	}
+	if hasExtKeyUsage(extKeyUsage, x509.ExtKeyUsageTimeStamping) {
+		if err := markTimeStamping(cert); err != nil {
+			return nil, err
+		}
+	}
+	if data.Version == 1 {
+		stripExtensions(cert)
+	}
+	return cert, nil
+}
+
+// FromX509 converts a parsed certificate back into a Certificate, the inverse of
+// NewCertificateTemplate. PrivateKey is left nil. The conversion is lossy: only the
+// first country, organization and unit are kept, AlternativeNames holds the DNS names
+// as stored, which includes the common name and is normalized to lower case, Usage
+// lists the usages explicitly even if the defaults were used, usages known to crypto/x509
+// without a name in this package are dropped, and Id, SerialNumber, SignatureAlg and SKIDMethod
+// are not recovered. AllowUnderscores and AllowWeakKey are set if the certificate needs them.
+func FromX509(cert *x509.Certificate) Certificate {
+	data := Certificate{
+		CommonName:       cert.Subject.CommonName,
+		AlternativeNames: cert.DNSNames,
+		IPAddresses:      cert.IPAddresses,
+		Usage:            usageNames(cert.KeyUsage, cert.ExtKeyUsage),
+		CA:               cert.IsCA,
+		ValidFrom:        cert.NotBefore,
+		ValidTo:          cert.NotAfter,
+		IssuerURLs:       cert.IssuingCertificateURL,
+		EmailAddresses:   cert.EmailAddresses,
+	}
+	for _, oid := range cert.UnknownExtKeyUsage {
+		data.UnknownExtKeyUsage = append(data.UnknownExtKeyUsage, oid.String())
+	}
+	for _, name := range cert.DNSNames {
+		if strings.Contains(name, "_") {
+			data.AllowUnderscores = true
+		}
+	}
+	if checkRSAKeyBits(cert.PublicKey, key.MinRSAKeyBits) != nil {
+		data.AllowWeakKey = true
+	}
+	if len(cert.Subject.Country) > 0 {
+		data.Country = cert.Subject.Country[0]
+	}
+	if len(cert.Subject.Organization) > 0 {
+		data.Organization = cert.Subject.Organization[0]
+	}
+	if len(cert.Subject.OrganizationalUnit) > 0 {
+		data.OrganizationalUnit = cert.Subject.OrganizationalUnit[0]
+	}
+	if cert.IsCA {
+		data.MaxPathLen = cert.MaxPathLen
+		data.MaxPathLenZero = cert.MaxPathLenZero
+	}
+	return data
+}
+
+// usageNames maps key usages back to the names accepted in Certificate.Usage, the
+// key usages sorted by name followed by the extended key usages in order.
+func usageNames(keyUsage x509.KeyUsage, extKeyUsage []x509.ExtKeyUsage) []string {
+	var names []string
+	for name, ku := range keyUsages {
+		if keyUsage&ku != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, eku := range extKeyUsage {
+		for name, e := range extKeyUsages {
+			if e == eku {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
 }
 
-func keyIdentifier(pub interface{}) []byte {
-	pbyte, _ := key.PublicKeyBitArray(pub)
-	hasher := sha1.New()
-	hasher.Write(pbyte)
-	return hasher.Sum(nil)
+// buildSubject returns the subject name of the certificate, it does not depend on the private key.
+// Empty attributes are omitted, if the whole subject is empty the x509 package marks the
+// alternative names extension critical as required by RFC 5280.
+func buildSubject(data Certificate) pkix.Name {
+	subject := pkix.Name{CommonName: data.CommonName}
+	if data.Country != "" {
+		subject.Country = []string{data.Country}
+	}
+	if data.Organization != "" {
+		subject.Organization = []string{data.Organization}
+	}
+	if data.OrganizationalUnit != "" {
+		subject.OrganizationalUnit = []string{data.OrganizationalUnit}
+	}
+	if data.EmailInSubject && len(data.EmailAddresses) > 0 {
+		subject.ExtraNames = append(subject.ExtraNames, pkix.AttributeTypeAndValue{
+			Type: oidEmailAddress,
+			// RFC 5280 wants an IA5String, crypto/x509 would pick UTF8String
+			Value: asn1.RawValue{Tag: asn1.TagIA5String, Bytes: []byte(data.EmailAddresses[0])},
+		})
+	}
+	// checked by NewCertificateTemplate
+	extra, _ := subjectAttributes(data)
+	subject.ExtraNames = append(subject.ExtraNames, extra...)
+	return subject
+}
+
+var oidEmailAddress = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}
+
+// checkEmailAddresses rejects addresses that are not a plain local@domain.
+func checkEmailAddresses(addresses []string) error {
+	for _, address := range addresses {
+		parsed, err := mail.ParseAddress(address)
+		if err != nil || parsed.Address != address {
+			return fmt.Errorf("invalid email address: %q", address)
+		}
+	}
+	return nil
+}
+
+// checkSigner verifies that signer is a CA that may sign certificates, a certificate
+// signed by anything else is rejected by every verifier.
+func checkSigner(signer *x509.Certificate) error {
+	if !signer.IsCA {
+		return fmt.Errorf("signer %v is not a CA", signer.Subject)
+	}
+	if signer.KeyUsage != 0 && signer.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return fmt.Errorf("signer %v lacks the certsign key usage", signer.Subject)
+	}
+	return nil
+}
+
+// buildValidity returns the validity period of the certificate. Explicit ValidFrom and
+// ValidTo take precedence, otherwise the period is computed from now backdated
+// with NotBeforeSkew to handle hosts with slightly skewed clocks.
+func buildValidity(data Certificate, now time.Time) (time.Time, time.Time) {
+	validFor := data.ValidFor
+	if validFor == 0 {
+		validFor = defaultValidFor
+		if isStringInList("codesigning", data.Usage) {
+			validFor = codeSigningValidFor
+		}
+	}
+	notBefore := data.ValidFrom
+	if notBefore.IsZero() {
+		skew := data.NotBeforeSkew
+		switch {
+		case skew == 0:
+			skew = defaultNotBeforeSkew
+		case skew < 0:
+			skew = 0
+		}
+		notBefore = now.Add(-skew)
+	}
+	notAfter := data.ValidTo
+	if notAfter.IsZero() {
+		notAfter = notBefore.Add(validFor)
+	}
+	return notBefore, notAfter
 }
 
+// ValidityWindow is a validity period relative to a reference time, e.g. valid from
+// tomorrow for 90 days for staged rollouts. A negative NotBeforeOffset backdates the
+// start to handle clock skew.
+type ValidityWindow struct {
+	NotBeforeOffset time.Duration
+	Duration        time.Duration
+}
+
+// Resolve returns the ValidFrom and ValidTo of the window counted from ref.
+func (w ValidityWindow) Resolve(ref time.Time) (time.Time, time.Time) {
+	validFrom := ref.Add(w.NotBeforeOffset)
+	return validFrom, validFrom.Add(w.Duration)
+}
+
+// SKIDMethod selects how the subject key identifier is computed from the public key.
+type SKIDMethod int
+
+const (
+	// SKIDSHA1 is method 1 of RFC 5280, the SHA-1 hash of the subjectPublicKey BIT STRING
+	// in the SubjectPublicKeyInfo, the same as openssl computes. It is the default.
+	SKIDSHA1 SKIDMethod = iota
+	// SKIDSHA256Truncated is the SHA-256 hash of the subjectPublicKey truncated to 160 bits.
+	SKIDSHA256Truncated
+	// SKIDSHA256 is the full SHA-256 hash of the subjectPublicKey.
+	SKIDSHA256
+	// SKIDLegacy is the SHA-1 hash of key.PublicKeyBitArray, kept for certificates
+	// that must match identifiers created by earlier versions. It gives the same
+	// result as SKIDSHA1 for RSA, ECDSA and Ed25519 keys.
+	SKIDLegacy
+	// SKIDTruncated64 is method 2 of RFC 5280, the four bits 0100 followed by the least
+	// significant 60 bits of the SHA-1 hash, for devices that require a short identifier.
+	SKIDTruncated64
+)
+
+func keyIdentifier(pub interface{}, method SKIDMethod) ([]byte, error) {
+	if method == SKIDLegacy {
+		return key.SubjectKeyId(pub)
+	}
+	pbyte, err := subjectPublicKeyBits(pub)
+	if err != nil {
+		return nil, err
+	}
+	switch method {
+	case SKIDSHA256Truncated:
+		sum := sha256.Sum256(pbyte)
+		return sum[:20], nil
+	case SKIDSHA256:
+		sum := sha256.Sum256(pbyte)
+		return sum[:], nil
+	case SKIDTruncated64:
+		sum := sha1.Sum(pbyte)
+		id := sum[len(sum)-8:]
+		id[0] = 0x40 | id[0]&0x0f
+		return id, nil
+	default:
+		sum := sha1.Sum(pbyte)
+		return sum[:], nil
+	}
+}
+
+// SetAuthorityKeyId sets the authority key identifier of leaf for signing with issuer,
+// for issuers not created by this package. The subject key identifier of issuer is
+// used, or if it has none one is computed from its public key with SKIDSHA1. An error
+// is returned if the public key of issuer is not supported.
+func SetAuthorityKeyId(leaf, issuer *x509.Certificate) error {
+	if len(issuer.SubjectKeyId) > 0 {
+		leaf.AuthorityKeyId = append([]byte(nil), issuer.SubjectKeyId...)
+		return nil
+	}
+	id, err := keyIdentifier(issuer.PublicKey, SKIDSHA1)
+	if err != nil {
+		return err
+	}
+	leaf.AuthorityKeyId = id
+	return nil
+}
+
+// subjectPublicKeyBits returns the content of the subjectPublicKey BIT STRING
+// of the DER encoded SubjectPublicKeyInfo for pub.
+func subjectPublicKeyBits(pub interface{}) ([]byte, error) {
+	spki, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	var info struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(spki, &info); err != nil {
+		return nil, err
+	}
+	return info.PublicKey.Bytes, nil
+}
+
+// signatureAlgorithm returns the signature algorithm for the key. Any crypto.Signer is
+// supported, such as a key kept in a HSM, the algorithm is then chosen from its public key.
+// The algType auto picks the hash matching the strength of the key.
 func signatureAlgorithm(algType string, privateKey interface{}) x509.SignatureAlgorithm {
-	switch privateKey.(type) {
-	case *rsa.PrivateKey:
+	var publicKey crypto.PublicKey
+	if signer, ok := privateKey.(crypto.Signer); ok {
+		publicKey = signer.Public()
+	}
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		if algType == "auto" {
+			algType = recommendedHashForRSA(k.N.BitLen())
+		}
 		return findRsaSignALg(algType)
-	case *ecdsa.PrivateKey:
+	case *ecdsa.PublicKey:
+		if algType == "auto" {
+			algType = recommendedHashForCurve(k.Curve)
+		}
+		if err := checkCurveHash(algType, k.Curve); err != nil {
+			logger.Printf("Warning: %v", err)
+		}
 		return findEcdsaSignALg(algType)
+	case ed25519.PublicKey:
+		// Ed25519 signs the message itself, there is no hash to choose
+		return x509.PureEd25519
 	default:
-		log.Fatal("Could not find any signature algorithm\n")
+		// left to crypto/x509, which fails to sign with an unsupported key
 		return x509.UnknownSignatureAlgorithm
 	}
 }
 
+// recommendedHashForCurve returns the hash matching the strength of the curve.
+func recommendedHashForCurve(curve elliptic.Curve) string {
+	switch curve {
+	case elliptic.P384():
+		return "SHA384"
+	case elliptic.P521():
+		return "SHA512"
+	default:
+		return "SHA256"
+	}
+}
+
+// recommendedHashForRSA returns the hash matching the strength of an RSA key of the
+// given length, following the security strengths of NIST SP 800-57.
+func recommendedHashForRSA(bits int) string {
+	switch {
+	case bits >= 15360:
+		return "SHA512"
+	case bits >= 7680:
+		return "SHA384"
+	default:
+		return "SHA256"
+	}
+}
+
+// checkCurveHash returns an error if the hash used for the signature does not
+// match the strength of the curve.
+func checkCurveHash(algType string, curve elliptic.Curve) error {
+	hash := algType
+	switch hash {
+	case "SHA1", "SHA256", "SHA384", "SHA512":
+	default:
+		hash = "SHA256"
+	}
+	if recommended := recommendedHashForCurve(curve); hash != recommended {
+		return fmt.Errorf("hash %s does not match the strength of curve %s, recommended hash is %s", hash, curve.Params().Name, recommended)
+	}
+	return nil
+}
+
 func findEcdsaSignALg(algType string) x509.SignatureAlgorithm {
 	switch algType {
 	case "SHA1":
@@ -121,6 +688,13 @@ func findEcdsaSignALg(algType string) x509.SignatureAlgorithm {
 	}
 }
 
+func checkSHA1Allowed(data Certificate) error {
+	if data.SignatureAlg == "SHA1" && !data.AllowInsecureSHA1 {
+		return fmt.Errorf("certificate %q: SignatureAlg (hashalg in the config file) is SHA1, which clients refuse to verify, set AllowInsecureSHA1 (allowinsecuresha1) to use it anyway", data.Id)
+	}
+	return nil
+}
+
 func findRsaSignALg(algType string) x509.SignatureAlgorithm {
 	switch algType {
 	case "SHA1":
@@ -136,6 +710,19 @@ func findRsaSignALg(algType string) x509.SignatureAlgorithm {
 	}
 }
 
+// normalizeDNSNames lower cases the names, trims whitespace and trailing dots and
+// removes duplicates, keeping the first occurrence.
+func normalizeDNSNames(names []string) []string {
+	var normalized []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimRight(strings.TrimSpace(name), "."))
+		if name != "" && !isStringInList(name, normalized) {
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized
+}
+
 func isStringInList(value string, list []string) bool {
 	for _, v := range list {
 		if v == value {
@@ -145,29 +732,72 @@ func isStringInList(value string, list []string) bool {
 	return false
 }
 
-func CheckCertificate(dnsName string, caBytes, interCaBytes, clientBytes []byte) bool {
+// CheckCertificate verifies the client certificate against the given root and intermediate
+// certificates. The intermediates are DER encoded or a PEM bundle. If keyUsages is given
+// the client certificate must be valid for at least one of them, otherwise server
+// authentication is required.
+func CheckCertificate(dnsName string, caBytes, interCaBytes, clientBytes []byte, keyUsages ...x509.ExtKeyUsage) bool {
+	_, certErr := verifyChain(dnsName, caBytes, interCaBytes, clientBytes, keyUsages)
+	if certErr != nil {
+		logger.Printf("Certificates do not verify: %v", certErr)
+		return false
+	}
+	logger.Printf("Certificates verify: OK")
+	return true
+}
+
+// BuildChain verifies the client certificate the same way as CheckCertificate and returns
+// the first verified chain, ordered from the client certificate to the root.
+func BuildChain(dnsName string, caBytes, interCaBytes, clientBytes []byte) ([]*x509.Certificate, error) {
+	chains, err := verifyChain(dnsName, caBytes, interCaBytes, clientBytes, nil)
+	if err != nil {
+		return nil, err
+	}
+	return chains[0], nil
+}
+
+// parseIntermediates parses DER encoded certificates or a PEM bundle.
+func parseIntermediates(data []byte) ([]*x509.Certificate, error) {
+	if isPem(data) {
+		return ParseCertificatesPem(data)
+	}
+	return x509.ParseCertificates(data)
+}
+
+func verifyChain(dnsName string, caBytes, interCaBytes, clientBytes []byte, keyUsages []x509.ExtKeyUsage) ([][]*x509.Certificate, error) {
 	rootPool := x509.NewCertPool()
-	rootCert, _ := x509.ParseCertificate(caBytes)
+	rootCert, err := x509.ParseCertificate(caBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %v", err)
+	}
 	rootPool.AddCert(rootCert)
 	interCaPool := x509.NewCertPool()
-	interCerts, _ := x509.ParseCertificates(interCaBytes)
+	interCerts, err := parseIntermediates(interCaBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse intermediate certificates: %v", err)
+	}
 	for _, cert := range interCerts {
 		interCaPool.AddCert(cert)
 	}
+	dnsName, err = toASCIIName(dnsName)
+	if err != nil {
+		return nil, err
+	}
 	opts := x509.VerifyOptions{
 		DNSName:       dnsName,
 		Roots:         rootPool,
 		Intermediates: interCaPool,
+		KeyUsages:     keyUsages,
 	}
-	clientCert, _ := x509.ParseCertificate(clientBytes)
-	_, certErr := clientCert.Verify(opts)
-	if certErr != nil {
-		log.Printf("Could not verify certificate: %v\n", clientCert.Subject.CommonName)
-		log.Println(certErr)
-		return false
+	clientCert, err := x509.ParseCertificate(clientBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %v", err)
 	}
-	log.Println("Certificates verify: OK")
-	return true
+	chains, err := clientCert.Verify(opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify certificate %v: %v", clientCert.Subject.CommonName, err)
+	}
+	return chains, nil
 }
 
 /* TODO to be added
@@ -186,16 +816,52 @@ KeyUsageDecipherOnly
 ExtKeyUsageAny
 ExtKeyUsageServerAuth
 ExtKeyUsageClientAuth
-ExtKeyUsageCodeSigning
-ExtKeyUsageEmailProtection
-ExtKeyUsageIPSECEndSystem
-ExtKeyUsageIPSECTunnel
-ExtKeyUsageIPSECUser
-ExtKeyUsageTimeStamping
 ExtKeyUsageOCSPSigning
-ExtKeyUsageMicrosoftServerGatedCrypto
-ExtKeyUsageNetscapeServerGatedCrypto
 */
+var keyUsages = map[string]x509.KeyUsage{
+	"crlsign":           x509.KeyUsageCRLSign,
+	"certsign":          x509.KeyUsageCertSign,
+	"encipherment":      x509.KeyUsageKeyEncipherment,
+	"signature":         x509.KeyUsageDigitalSignature,
+	"contentcommitment": x509.KeyUsageContentCommitment,
+}
+
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	"clientauth":      x509.ExtKeyUsageClientAuth,
+	"serverauth":      x509.ExtKeyUsageServerAuth,
+	"msgatedcrypto":   x509.ExtKeyUsageMicrosoftServerGatedCrypto,
+	"nsgatedcrypto":   x509.ExtKeyUsageNetscapeServerGatedCrypto,
+	"ipsecendsystem":  x509.ExtKeyUsageIPSECEndSystem,
+	"ipsectunnel":     x509.ExtKeyUsageIPSECTunnel,
+	"ipsecuser":       x509.ExtKeyUsageIPSECUser,
+	"timestamping":    x509.ExtKeyUsageTimeStamping,
+	"codesigning":     x509.ExtKeyUsageCodeSigning,
+	"emailprotection": x509.ExtKeyUsageEmailProtection,
+}
+
+var oidExtKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37}
+
+// markTimeStamping makes the extended key usage of a timestamping authority critical,
+// RFC 3161 requires id-kp-timeStamping to be its only extended key usage.
+func markTimeStamping(cert *x509.Certificate) error {
+	if len(cert.ExtKeyUsage) != 1 || len(cert.UnknownExtKeyUsage) > 0 {
+		return errors.New("timestamping must be the only extended key usage")
+	}
+	value, err := asn1.Marshal([]asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 8}})
+	if err != nil {
+		return err
+	}
+	// crypto/x509 never marks the extension critical, it skips its own for this one
+	cert.ExtraExtensions = append(cert.ExtraExtensions, pkix.Extension{Id: oidExtKeyUsage, Critical: true, Value: value})
+	return nil
+}
+
+func isKnownUsage(usage string) bool {
+	_, isKey := keyUsages[usage]
+	_, isExt := extKeyUsages[usage]
+	return isKey || isExt
+}
+
 func getUsage(usage []string, ca bool) (x509.KeyUsage, []x509.ExtKeyUsage) {
 	if len(usage) == 0 {
 		return getDefaultKeyUsage(ca), getDefaultExtKeyUsage(ca)
@@ -203,26 +869,37 @@ func getUsage(usage []string, ca bool) (x509.KeyUsage, []x509.ExtKeyUsage) {
 	var keyUsage x509.KeyUsage
 	var extKeyUsage []x509.ExtKeyUsage
 	for _, key := range usage {
-		switch key {
-		case "crlsign":
-			keyUsage |= x509.KeyUsageCRLSign
-		case "certsign":
-			keyUsage |= x509.KeyUsageCertSign
-		case "encipherment":
-			keyUsage |= x509.KeyUsageKeyEncipherment
-		case "signature":
-			keyUsage |= x509.KeyUsageDigitalSignature
-		case "contentcommitment":
-			keyUsage |= x509.KeyUsageContentCommitment
-		case "clientauth":
-			extKeyUsage = append(extKeyUsage, x509.ExtKeyUsageClientAuth)
-		case "serverauth":
-			extKeyUsage = append(extKeyUsage, x509.ExtKeyUsageServerAuth)
+		if ku, ok := keyUsages[key]; ok {
+			keyUsage |= ku
+		}
+		if eku, ok := extKeyUsages[key]; ok && !hasExtKeyUsage(extKeyUsage, eku) {
+			extKeyUsage = append(extKeyUsage, eku)
 		}
 	}
 	return keyUsage, extKeyUsage
 }
 
+func hasExtKeyUsage(list []x509.ExtKeyUsage, eku x509.ExtKeyUsage) bool {
+	for _, e := range list {
+		if e == eku {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeUsages returns the usages in a followed by those in b that are not already present,
+// useful to layer role specific usages on top of a base profile.
+func MergeUsages(a, b []string) []string {
+	var merged []string
+	for _, u := range append(append([]string{}, a...), b...) {
+		if !isStringInList(u, merged) {
+			merged = append(merged, u)
+		}
+	}
+	return merged
+}
+
 func getDefaultKeyUsage(ca bool) x509.KeyUsage {
 	if ca {
 		return x509.KeyUsageCRLSign | x509.KeyUsageCertSign
@@ -237,12 +914,16 @@ func getDefaultExtKeyUsage(ca bool) []x509.ExtKeyUsage {
 	return []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
 }
 
-func WritePemToFile(b []byte, fileName string) {
+// WritePemToFile writes the DER encoded certificate b PEM encoded to fileName.
+func WritePemToFile(b []byte, fileName string) error {
 	certFile, err := os.Create(fileName)
-	defer certFile.Close()
 	if err != nil {
-		log.Fatalf("Failed to open %s for writing cerificate: %s\n", fileName, err)
+		return fmt.Errorf("failed to open %s for writing certificate: %v", fileName, err)
+	}
+	defer certFile.Close()
+	if err := pem.Encode(certFile, &pem.Block{Type: "CERTIFICATE", Bytes: b}); err != nil {
+		return fmt.Errorf("failed to write certificate to %s: %v", fileName, err)
 	}
-	pem.Encode(certFile, &pem.Block{Type: "CERTIFICATE", Bytes: b})
-	fmt.Printf("wrote certificate %s to file\n", fileName)
+	logger.Printf("wrote certificate %s to file", fileName)
+	return nil
 }
@@ -1,20 +1,32 @@
 package certificate
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/elliptic"
+	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/hex"
 	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	mathrand "math/rand/v2"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/chrjoh/certificateBar/key"
+	"github.com/ignalina/certificateBar/v2/key"
 )
 
 var (
 	OU = asn1.ObjectIdentifier{2, 5, 4, 11}
 )
+
 var pemPublicKey = `-----BEGIN PUBLIC KEY-----
 MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA3VoPN9PKUjKFLMwOge6+
 wnDi8sbETGIx2FKXGgqtAKpzmem53kRGEQg8WeqRmp12wgp74TGpkEXsGae7RS1k
@@ -26,16 +38,81 @@ FF53oIpvxe/SCOymfWq/LW849Ytv3Xwod0+wzAP8STXG4HSELS4UedPYeHJJJYcZ
 -----END PUBLIC KEY-----
 `
 
+func mustKeyIdentifier(t *testing.T, pub interface{}, method SKIDMethod) []byte {
+	t.Helper()
+	id, err := keyIdentifier(pub, method)
+	if err != nil {
+		t.Fatalf("failed to compute key identifier: %v", err)
+	}
+	return id
+}
+
 func TestSubjectKeyId(t *testing.T) {
 	block, _ := pem.Decode([]byte(pemPublicKey))
 	pub, _ := x509.ParsePKIXPublicKey(block.Bytes)
-	data := keyIdentifier(pub)
+	data := mustKeyIdentifier(t, pub, SKIDSHA1)
 	s := hex.EncodeToString(data)
 	if s != "103cb6fde54563169f15f5eecd414506410a77ad" {
 		t.Fatalf("Wrong subjectKeyId, got: %s, wanted: 103cb6fde54563169f15f5eecd414506410a77ad", s)
 	}
 }
 
+func TestSetAuthorityKeyId(t *testing.T) {
+	caTemplate, caPriv := createCA()
+	ca := parseCert(t, Sign(caTemplate, caTemplate, key.PublicKey(caPriv), caPriv))
+	client, _ := createClient()
+	if err := SetAuthorityKeyId(client, ca); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ca.SubjectKeyId) == 0 || !bytes.Equal(client.AuthorityKeyId, ca.SubjectKeyId) {
+		t.Fatalf("got AKI %x, want the issuer SKI %x", client.AuthorityKeyId, ca.SubjectKeyId)
+	}
+
+	// an issuer signed elsewhere without SKI
+	ca.SubjectKeyId = nil
+	client, clientPriv := createClient()
+	if err := SetAuthorityKeyId(client, ca); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := mustKeyIdentifier(t, key.PublicKey(caPriv), SKIDSHA1)
+	if !bytes.Equal(client.AuthorityKeyId, want) {
+		t.Fatalf("got AKI %x, want %x", client.AuthorityKeyId, want)
+	}
+	leaf := parseCert(t, Sign(client, ca, key.PublicKey(clientPriv), caPriv))
+	if !bytes.Equal(leaf.AuthorityKeyId, want) {
+		t.Fatalf("got AKI %x in the signed certificate, want %x", leaf.AuthorityKeyId, want)
+	}
+
+	ca.PublicKey = struct{}{}
+	if err := SetAuthorityKeyId(client, ca); err == nil {
+		t.Fatal("expected an error for an unsupported issuer key")
+	}
+}
+
+func TestBuildChain(t *testing.T) {
+	ca, caPriv := createCA()
+	caBytes := Sign(ca, ca, key.PublicKey(caPriv), caPriv)
+	interCa, interCaPriv := createInterCA()
+	interCaBytes := Sign(interCa, ca, key.PublicKey(interCaPriv), caPriv)
+	client, clientPriv := createClient()
+	clientBytes := Sign(client, interCa, key.PublicKey(clientPriv), interCaPriv)
+	chain, err := BuildChain("www.foo.se", caBytes, interCaBytes, clientBytes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(chain) != 3 {
+		t.Fatalf("got chain of length %d, want 3", len(chain))
+	}
+	for i, want := range [][]byte{clientBytes, interCaBytes, caBytes} {
+		if !bytes.Equal(chain[i].Raw, want) {
+			t.Fatalf("wrong certificate at position %d: %v", i, chain[i].Subject)
+		}
+	}
+	if _, err := BuildChain("www.other.se", caBytes, interCaBytes, clientBytes); err == nil {
+		t.Fatal("expected error for a name not in the certificate")
+	}
+}
+
 func TestCreateCertificateCahin(t *testing.T) {
 	ca, caPriv := createCA()
 	caPub := key.PublicKey(caPriv)
@@ -77,7 +154,7 @@ func TestValidSignedCertificateCahin(t *testing.T) {
 	for _, name := range []string{"", "www.baz.se", "www.foo.se", "www.bar.se"} {
 		chainOk := CheckCertificate(name, caBytes, interCaBytes, clientBytes)
 		if !chainOk {
-			t.Fatalf("Failed to verify client for dnsName: ", name)
+			t.Fatalf("Failed to verify client for dnsName: %v", name)
 		}
 	}
 }
@@ -90,6 +167,7 @@ func createCA() (*x509.Certificate, interface{}) {
 		OrganizationalUnit: "WebCA",
 		CA:                 true,
 		PrivateKey:         caPriv,
+		AllowWeakKey:       true,
 		ValidFrom:          time.Now(),
 		ValidTo:            time.Now().AddDate(1, 0, 0),
 	}
@@ -106,6 +184,7 @@ func createInterCA() (*x509.Certificate, interface{}) {
 		OrganizationalUnit: "WebInterCA",
 		CA:                 true,
 		PrivateKey:         interCaPriv,
+		AllowWeakKey:       true,
 		ValidFrom:          time.Now(),
 		ValidTo:            time.Now().AddDate(1, 0, 0),
 	}
@@ -123,6 +202,7 @@ func createClient() (*x509.Certificate, interface{}) {
 		CommonName:         "www.baz.se",
 		AlternativeNames:   []string{"www.foo.se", "www.bar.se"},
 		PrivateKey:         clientPriv,
+		AllowWeakKey:       true,
 		ValidFrom:          time.Now(),
 		ValidTo:            time.Now().AddDate(1, 0, 0),
 	}
@@ -137,3 +217,635 @@ func getPkixValue(values []pkix.AttributeTypeAndValue, key asn1.ObjectIdentifier
 	}
 	return ""
 }
+
+func TestCheckCertificateExtKeyUsage(t *testing.T) {
+	ca, caPriv := createCA()
+	caBytes := Sign(ca, ca, key.PublicKey(caPriv), caPriv)
+	serverPriv := key.GenerateKey("RSA", 1024)
+	serverData := Certificate{
+		Id:                 "four",
+		Country:            "SE",
+		Organization:       "test",
+		OrganizationalUnit: "Web",
+		CommonName:         "www.baz.se",
+		AlternativeNames:   []string{"www.baz.se"},
+		Usage:              []string{"serverauth"},
+		PrivateKey:         serverPriv,
+		AllowWeakKey:       true,
+		ValidFrom:          time.Now(),
+		ValidTo:            time.Now().AddDate(1, 0, 0),
+	}
+	server := mustCreateTemplate(t, serverData)
+	serverBytes := Sign(server, ca, key.PublicKey(serverPriv), caPriv)
+	if CheckCertificate("www.baz.se", caBytes, nil, serverBytes, x509.ExtKeyUsageClientAuth) {
+		t.Fatal("server only certificate verified for client authentication")
+	}
+	if !CheckCertificate("www.baz.se", caBytes, nil, serverBytes, x509.ExtKeyUsageServerAuth) {
+		t.Fatal("server certificate failed to verify for server authentication")
+	}
+}
+
+func TestValidityExplicit(t *testing.T) {
+	from := time.Date(2015, 11, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2017, 11, 1, 0, 0, 0, 0, time.UTC)
+	data := Certificate{ValidFrom: from, ValidTo: to, ValidFor: time.Hour, NotBeforeSkew: time.Hour}
+	notBefore, notAfter := buildValidity(data, time.Now())
+	if !notBefore.Equal(from) || !notAfter.Equal(to) {
+		t.Fatalf("got: %v - %v, want %v - %v", notBefore, notAfter, from, to)
+	}
+}
+
+func TestValidityDurationDefaultSkew(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	data := Certificate{ValidFor: 24 * time.Hour}
+	notBefore, notAfter := buildValidity(data, now)
+	if !notBefore.Equal(now.Add(-5 * time.Minute)) {
+		t.Fatalf("got: %v, want %v", notBefore, now.Add(-5*time.Minute))
+	}
+	if notAfter.Sub(notBefore) != 24*time.Hour {
+		t.Fatalf("got: %v, want 24h", notAfter.Sub(notBefore))
+	}
+}
+
+func TestValidityDurationCustomSkew(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	data := Certificate{ValidFor: time.Hour, NotBeforeSkew: time.Minute}
+	notBefore, notAfter := buildValidity(data, now)
+	if !notBefore.Equal(now.Add(-time.Minute)) || !notAfter.Equal(now.Add(59*time.Minute)) {
+		t.Fatalf("got: %v - %v", notBefore, notAfter)
+	}
+}
+
+func TestValidityNoSkew(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	data := Certificate{ValidFor: time.Hour, NotBeforeSkew: -1}
+	notBefore, notAfter := buildValidity(data, now)
+	if !notBefore.Equal(now) || !notAfter.Equal(now.Add(time.Hour)) {
+		t.Fatalf("got: %v - %v", notBefore, notAfter)
+	}
+}
+
+func TestValidityExplicitFromWithDuration(t *testing.T) {
+	from := time.Date(2015, 11, 1, 0, 0, 0, 0, time.UTC)
+	data := Certificate{ValidFrom: from, ValidFor: time.Hour}
+	notBefore, notAfter := buildValidity(data, time.Now())
+	if !notBefore.Equal(from) || !notAfter.Equal(from.Add(time.Hour)) {
+		t.Fatalf("got: %v - %v", notBefore, notAfter)
+	}
+}
+
+func TestBuildSubjectWithoutKey(t *testing.T) {
+	data := Certificate{
+		Country:            "SE",
+		Organization:       "test",
+		OrganizationalUnit: "WebCA",
+		CommonName:         "www.foo.se",
+	}
+	subject := buildSubject(data)
+	if subject.CommonName != "www.foo.se" {
+		t.Fatalf("got: %v, want www.foo.se", subject.CommonName)
+	}
+	if subject.OrganizationalUnit[0] != "WebCA" || subject.Country[0] != "SE" || subject.Organization[0] != "test" {
+		t.Fatalf("wrong subject: %v", subject)
+	}
+}
+
+func TestBuildValidityDefaultWithoutKey(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	notBefore, notAfter := buildValidity(Certificate{}, now)
+	if notAfter.Sub(notBefore) != 365*24*time.Hour {
+		t.Fatalf("got: %v, want one year", notAfter.Sub(notBefore))
+	}
+}
+
+func TestValidityWindowResolve(t *testing.T) {
+	ref := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		window   ValidityWindow
+		from, to time.Time
+	}{
+		{"future start", ValidityWindow{NotBeforeOffset: 24 * time.Hour, Duration: 90 * 24 * time.Hour}, ref.AddDate(0, 0, 1), ref.AddDate(0, 0, 91)},
+		{"backdated", ValidityWindow{NotBeforeOffset: -time.Hour, Duration: 24 * time.Hour}, ref.Add(-time.Hour), ref.Add(23 * time.Hour)},
+	}
+	for _, tt := range tests {
+		from, to := tt.window.Resolve(ref)
+		if !from.Equal(tt.from) || !to.Equal(tt.to) {
+			t.Errorf("%s: got %v to %v, want %v to %v", tt.name, from, to, tt.from, tt.to)
+		}
+	}
+}
+
+func TestFromX509RoundTrip(t *testing.T) {
+	caPriv := key.GenerateKey("RSA", 1024)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ca := mustCreateTemplate(t, Certificate{Id: "ca", CommonName: "ca", CA: true, PrivateKey: caPriv, AllowWeakKey: true, ValidFrom: from, ValidTo: from.AddDate(5, 0, 0)})
+	data := Certificate{
+		Id:                 "leaf",
+		Country:            "SE",
+		Organization:       "test",
+		OrganizationalUnit: "Web",
+		CommonName:         "www.foo.se",
+		AlternativeNames:   []string{"foo.se", "www.foo.se"},
+		Usage:              []string{"encipherment", "signature", "serverauth", "clientauth"},
+		PrivateKey:         key.GenerateKey("RSA", 1024),
+		AllowWeakKey:       true,
+		ValidFrom:          from,
+		ValidTo:            from.AddDate(1, 0, 0),
+	}
+	template := mustCreateTemplate(t, data)
+	cert, err := x509.ParseCertificate(Sign(template, ca, key.PublicKey(data.PrivateKey), caPriv))
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	got := FromX509(cert)
+	if got.PrivateKey != nil {
+		t.Fatal("private key is set")
+	}
+	// Id and PrivateKey are not recovered
+	data.Id, data.PrivateKey = "", nil
+	if !reflect.DeepEqual(got, data) {
+		t.Fatalf("got: %+v\nwant: %+v", got, data)
+	}
+}
+
+func TestFromX509CA(t *testing.T) {
+	priv := key.GenerateKey("P256", 0)
+	template := mustCreateTemplate(t, Certificate{Id: "ca", CommonName: "ca", CA: true, MaxPathLenZero: true, PrivateKey: priv, AllowWeakKey: true})
+	cert, _ := x509.ParseCertificate(Sign(template, template, key.PublicKey(priv), priv))
+	got := FromX509(cert)
+	if !got.CA || !got.MaxPathLenZero || !reflect.DeepEqual(got.Usage, []string{"certsign", "crlsign"}) {
+		t.Fatalf("got: %+v, want a CA with path length zero and the default CA usages", got)
+	}
+}
+
+func TestEmptySubjectOmitsAttributes(t *testing.T) {
+	subject := buildSubject(Certificate{CommonName: "www.foo.se"})
+	if subject.Country != nil || subject.Organization != nil || subject.OrganizationalUnit != nil {
+		t.Fatalf("empty attributes present in subject: %v", subject)
+	}
+	if len(subject.ToRDNSequence()) != 1 {
+		t.Fatalf("got: %v, want only common name", subject.ToRDNSequence())
+	}
+}
+
+func TestEmptySubjectCriticalSAN(t *testing.T) {
+	ca, caPriv := createCA()
+	priv := key.GenerateKey("RSA", 1024)
+	data := Certificate{
+		Id:               "four",
+		AlternativeNames: []string{"www.foo.se"},
+		PrivateKey:       priv,
+		AllowWeakKey:     true,
+	}
+	template := mustCreateTemplate(t, data)
+	cert, err := x509.ParseCertificate(Sign(template, ca, key.PublicKey(priv), caPriv))
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	if len(cert.Subject.Names) != 0 {
+		t.Fatalf("got: %v, want empty subject", cert.Subject)
+	}
+	sanOid := asn1.ObjectIdentifier{2, 5, 29, 17}
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(sanOid) {
+			if !ext.Critical {
+				t.Fatal("SAN extension not critical for empty subject")
+			}
+			return
+		}
+	}
+	t.Fatal("SAN extension missing")
+}
+
+func TestRecommendedHashForCurve(t *testing.T) {
+	if err := checkCurveHash("SHA256", elliptic.P256()); err != nil {
+		t.Fatalf("unexpected warning for P-256 with SHA256: %v", err)
+	}
+	if err := checkCurveHash("SHA1", elliptic.P521()); err == nil {
+		t.Fatal("expected warning for P-521 with SHA1")
+	}
+	if h := recommendedHashForCurve(elliptic.P384()); h != "SHA384" {
+		t.Fatalf("got: %v, want SHA384", h)
+	}
+}
+
+func TestSignatureAlgorithmWarnsOnCurveMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(log.New(&buf, "", 0))
+	defer SetLogger(nil)
+	signatureAlgorithm("SHA256", key.GenerateKey("P256", 0))
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected warning: %s", buf.String())
+	}
+	alg := signatureAlgorithm("SHA1", key.GenerateKey("P521", 0))
+	if alg != x509.ECDSAWithSHA1 {
+		t.Fatalf("got: %v, want %v", alg, x509.ECDSAWithSHA1)
+	}
+	if !strings.Contains(buf.String(), "P-521") {
+		t.Fatalf("missing warning for P-521 with SHA1, got: %s", buf.String())
+	}
+}
+
+// recordingLogger keeps the messages written to it.
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+func TestSetLogger(t *testing.T) {
+	rec := &recordingLogger{}
+	SetLogger(rec)
+	defer SetLogger(nil)
+	ca, caPriv := createCA()
+	caBytes := Sign(ca, ca, key.PublicKey(caPriv), caPriv)
+	interCa, interCaPriv := createInterCA()
+	interCaBytes := Sign(interCa, ca, key.PublicKey(interCaPriv), caPriv)
+	client, clientPriv := createClient()
+	clientBytes := Sign(client, interCa, key.PublicKey(clientPriv), interCaPriv)
+	if !CheckCertificate("www.foo.se", caBytes, interCaBytes, clientBytes) {
+		t.Fatal("expected the chain to verify")
+	}
+	if CheckCertificate("www.other.se", caBytes, interCaBytes, clientBytes) {
+		t.Fatal("expected the chain not to verify for www.other.se")
+	}
+	if len(rec.messages) != 2 || rec.messages[0] != "Certificates verify: OK" || !strings.Contains(rec.messages[1], "www.other.se") {
+		t.Fatalf("got messages %q", rec.messages)
+	}
+
+	SetLogger(nil)
+	CheckCertificate("www.foo.se", caBytes, interCaBytes, clientBytes)
+	if len(rec.messages) != 2 {
+		t.Fatalf("got messages %q after resetting the logger", rec.messages)
+	}
+}
+
+func TestSignPanics(t *testing.T) {
+	client, clientPriv := createClient()
+	client.NotAfter = client.NotBefore
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Sign to panic")
+		}
+	}()
+	Sign(client, client, key.PublicKey(clientPriv), clientPriv)
+}
+
+func TestOmitBasicConstraints(t *testing.T) {
+	basicConstraints := asn1.ObjectIdentifier{2, 5, 29, 19}
+	ca, caPriv := createCA()
+	for _, isCA := range []bool{false, true} {
+		priv := key.GenerateKey("RSA", 1024)
+		data := Certificate{Id: "four", CommonName: "www.foo.se", CA: isCA, OmitBasicConstraints: true, PrivateKey: priv, AllowWeakKey: true}
+		template := mustCreateTemplate(t, data)
+		cert, _ := x509.ParseCertificate(Sign(template, ca, key.PublicKey(priv), caPriv))
+		present := false
+		for _, ext := range cert.Extensions {
+			if ext.Id.Equal(basicConstraints) {
+				present = true
+			}
+		}
+		if present != isCA {
+			t.Fatalf("CA: %v, got basic constraints present: %v", isCA, present)
+		}
+	}
+}
+
+func mustCreateTemplate(t testing.TB, data Certificate) *x509.Certificate {
+	t.Helper()
+	cert, err := NewCertificateTemplate(data)
+	if err != nil {
+		t.Fatalf("failed to create template: %v", err)
+	}
+	return cert
+}
+
+func TestSubjectKeyIdMethod(t *testing.T) {
+	block, _ := pem.Decode([]byte(pemPublicKey))
+	pub, _ := x509.ParsePKIXPublicKey(block.Bytes)
+	sha1Id := mustKeyIdentifier(t, pub, SKIDSHA1)
+	truncated := mustKeyIdentifier(t, pub, SKIDSHA256Truncated)
+	full := mustKeyIdentifier(t, pub, SKIDSHA256)
+	if len(sha1Id) != 20 || len(truncated) != 20 || len(full) != 32 {
+		t.Fatalf("wrong lengths: %d %d %d", len(sha1Id), len(truncated), len(full))
+	}
+	if bytes.Equal(sha1Id, truncated) {
+		t.Fatal("SHA-256 key id equals the SHA-1 key id")
+	}
+	if !bytes.Equal(truncated, full[:20]) {
+		t.Fatal("truncated key id is not the prefix of the full SHA-256 key id")
+	}
+	priv := key.GenerateKey("P256", 0)
+	template := mustCreateTemplate(t, Certificate{Id: "one", PrivateKey: priv, AllowWeakKey: true, SKIDMethod: SKIDSHA256})
+	if !bytes.Equal(template.SubjectKeyId, mustKeyIdentifier(t, key.PublicKey(priv), SKIDSHA256)) {
+		t.Fatal("template does not use the selected key id method")
+	}
+}
+
+var opensslRSACertPem = `-----BEGIN CERTIFICATE-----
+MIICPjCCAaegAwIBAgIUetYeTcmNtoZf9Kg4oIc+gRAqxVIwDQYJKoZIhvcNAQEL
+BQAwMTETMBEGA1UEAwwKd3d3LmZvby5zZTENMAsGA1UECgwEdGVzdDELMAkGA1UE
+BhMCU0UwHhcNMjYxMDE2MDgwOTA5WhcNMzYxMDEzMDgwOTA5WjAxMRMwEQYDVQQD
+DAp3d3cuZm9vLnNlMQ0wCwYDVQQKDAR0ZXN0MQswCQYDVQQGEwJTRTCBnzANBgkq
+hkiG9w0BAQEFAAOBjQAwgYkCgYEA2JQsXsycTNJjwTE5cc6C7TW8iJ5/a/5vswGl
+RHpaaUQvg+RxwN5EKKo1qa8j529jgaSLj4CrOo1tm9nRDNHqlt7UKQUTfBgw/Fnz
+ddQ0BmcSu0Iptb5Pa0oB0giVX+AXP356OTXkD0LwS15GIXuPyIr5vDsK/8EjfxPe
+MLQiB6kCAwEAAaNTMFEwHQYDVR0OBBYEFO4gmw7PFYuL+CkiXNbb9CRmdETOMB8G
+A1UdIwQYMBaAFO4gmw7PFYuL+CkiXNbb9CRmdETOMA8GA1UdEwEB/wQFMAMBAf8w
+DQYJKoZIhvcNAQELBQADgYEApFw161Xv/Pl3YGbjEgLg47zuRjKX1RICIVO40bdF
+k8Ulyh+cmCotALhy0VzoXdElAsRzJWgM97V6FtWV4JNsZFj6ULxVhv06wBDK9urN
+5b9EkPZf//VhNKw5UmZvEyFEwrdWfO7Ng+H4S71j2H+vPt6xsLJ9l/NNok7ao9sK
+4/w=
+-----END CERTIFICATE-----`
+
+// The expected values are the Subject Key Identifier printed by openssl x509 -text.
+func TestSubjectKeyIdMatchesOpenssl(t *testing.T) {
+	tests := map[string]string{
+		opensslRSACertPem: "EE:20:9B:0E:CF:15:8B:8B:F8:29:22:5C:D6:DB:F4:24:66:74:44:CE",
+		opensslCertPem:    "9F:E8:13:68:6C:9F:41:1B:FC:5A:95:99:18:6B:A5:B6:5F:4F:97:E2",
+	}
+	for certPem, want := range tests {
+		cert, err := parseCertificatePem([]byte(certPem))
+		if err != nil {
+			t.Fatalf("failed to parse certificate: %v", err)
+		}
+		if got := colonHex(mustKeyIdentifier(t, cert.PublicKey, SKIDSHA1)); got != want {
+			t.Fatalf("got: %s, want %s", got, want)
+		}
+		if got := colonHex(mustKeyIdentifier(t, cert.PublicKey, SKIDLegacy)); got != want {
+			t.Fatalf("legacy got: %s, want %s", got, want)
+		}
+	}
+}
+
+func TestSubjectKeyIdTruncated64(t *testing.T) {
+	tests := map[string]string{
+		opensslRSACertPem: "46:DB:F4:24:66:74:44:CE",
+		opensslCertPem:    "48:6B:A5:B6:5F:4F:97:E2",
+	}
+	for certPem, want := range tests {
+		cert, _ := parseCertificatePem([]byte(certPem))
+		if got := colonHex(mustKeyIdentifier(t, cert.PublicKey, SKIDTruncated64)); got != want {
+			t.Fatalf("got: %s, want %s", got, want)
+		}
+	}
+}
+
+// fakeSigner hides the concrete key type the same way a HSM backed crypto.Signer does.
+type fakeSigner struct {
+	key *rsa.PrivateKey
+}
+
+func (f fakeSigner) Public() crypto.PublicKey {
+	return f.key.Public()
+}
+
+func (f fakeSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return f.key.Sign(rand, digest, opts)
+}
+
+func TestSignWithCryptoSigner(t *testing.T) {
+	signer := fakeSigner{key: key.GenerateKey("RSA", 1024).(*rsa.PrivateKey)}
+	if alg := signatureAlgorithm("SHA384", signer); alg != x509.SHA384WithRSA {
+		t.Fatalf("got: %v, want %v", alg, x509.SHA384WithRSA)
+	}
+	ca := mustCreateTemplate(t, Certificate{Id: "one", OrganizationalUnit: "HSMCA", CA: true, PrivateKey: signer, AllowWeakKey: true})
+	caBytes := Sign(ca, ca, key.PublicKey(signer), signer)
+	client, clientPriv := createClient()
+	clientBytes := Sign(client, ca, key.PublicKey(clientPriv), signer)
+	if !CheckCertificate("www.baz.se", caBytes, nil, clientBytes) {
+		t.Fatal("certificate signed with crypto.Signer failed to verify")
+	}
+}
+
+func TestGetUsageDeduplicatesExtKeyUsage(t *testing.T) {
+	_, extKeyUsage := getUsage([]string{"serverauth", "signature", "serverauth"}, false)
+	if len(extKeyUsage) != 1 || extKeyUsage[0] != x509.ExtKeyUsageServerAuth {
+		t.Fatalf("got: %v, want a single serverauth", extKeyUsage)
+	}
+}
+
+func TestGetUsageExtKeyUsages(t *testing.T) {
+	tests := []struct {
+		usage string
+		want  x509.ExtKeyUsage
+	}{
+		{"msgatedcrypto", x509.ExtKeyUsageMicrosoftServerGatedCrypto},
+		{"nsgatedcrypto", x509.ExtKeyUsageNetscapeServerGatedCrypto},
+		{"ipsecendsystem", x509.ExtKeyUsageIPSECEndSystem},
+		{"ipsectunnel", x509.ExtKeyUsageIPSECTunnel},
+		{"ipsecuser", x509.ExtKeyUsageIPSECUser},
+	}
+	for _, tt := range tests {
+		_, extKeyUsage := getUsage([]string{tt.usage}, false)
+		if len(extKeyUsage) != 1 || extKeyUsage[0] != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.usage, extKeyUsage, tt.want)
+		}
+	}
+}
+
+func TestMergeUsages(t *testing.T) {
+	merged := MergeUsages([]string{"signature", "serverauth"}, []string{"serverauth", "clientauth"})
+	want := []string{"signature", "serverauth", "clientauth"}
+	if !reflect.DeepEqual(merged, want) {
+		t.Fatalf("got: %v, want %v", merged, want)
+	}
+}
+
+func TestSerialNumberFromId(t *testing.T) {
+	tests := map[string]int64{
+		"0x1A": 26,
+		"255":  255,
+		"ca":   0x6361,
+	}
+	for id, want := range tests {
+		serial, err := serialNumber(id)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", id, err)
+		}
+		if serial.Int64() != want {
+			t.Fatalf("%s: got: %v, want %d", id, serial, want)
+		}
+	}
+	first, _ := serialNumber("")
+	second, _ := serialNumber("")
+	if first.Sign() <= 0 || first.Cmp(second) == 0 {
+		t.Fatalf("got: %v and %v, want two different random serials", first, second)
+	}
+	if _, err := serialNumber("0xZZ"); err == nil {
+		t.Fatal("expected error for invalid hex serial")
+	}
+}
+
+func TestSignDeterministicWithRandReader(t *testing.T) {
+	defer func(r io.Reader) { RandReader = r }(RandReader)
+	caPriv := key.GenerateKey("RSA", 1024)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sign := func(priv interface{}) []byte {
+		RandReader = mathrand.NewChaCha8([32]byte{3})
+		// an empty id gives a random serial drawn from RandReader
+		template := mustCreateTemplate(t, Certificate{CommonName: "ca", CA: true, PrivateKey: caPriv, AllowWeakKey: true, ValidFrom: from, ValidTo: from.AddDate(1, 0, 0)})
+		client := mustCreateTemplate(t, Certificate{CommonName: "www.foo.se", PrivateKey: priv, AllowWeakKey: true, ValidFrom: from, ValidTo: from.AddDate(1, 0, 0)})
+		return Sign(client, template, key.PublicKey(priv), caPriv)
+	}
+	clientPriv := key.GenerateKey("RSA", 1024)
+	first := sign(clientPriv)
+	if !bytes.Equal(first, sign(clientPriv)) {
+		t.Fatal("certificates signed with the same seed differ")
+	}
+	if bytes.Equal(first, sign(key.GenerateKey("RSA", 1024))) {
+		t.Fatal("certificates for different keys are equal")
+	}
+}
+
+func TestSignChecksSigner(t *testing.T) {
+	caPriv := key.GenerateKey("RSA", 1024)
+	leaf := mustCreateTemplate(t, Certificate{Id: "one", CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: caPriv, AllowWeakKey: true})
+	noCertSign := mustCreateTemplate(t, Certificate{Id: "two", CommonName: "ca", CA: true, Usage: []string{"crlsign"}, PrivateKey: caPriv, AllowWeakKey: true})
+	client, clientPriv := createClient()
+	for _, signer := range []*x509.Certificate{leaf, noCertSign} {
+		if _, err := SignCertificate(client, signer, key.PublicKey(clientPriv), caPriv); err == nil {
+			t.Fatalf("signed with %v", signer.Subject)
+		}
+	}
+	defer func(skip bool) { UnsafeSkipSignerChecks = skip }(UnsafeSkipSignerChecks)
+	UnsafeSkipSignerChecks = true
+	if _, err := SignCertificate(client, leaf, key.PublicKey(clientPriv), caPriv); err != nil {
+		t.Fatalf("failed to sign with checks skipped: %v", err)
+	}
+}
+
+func TestSignatureAlgorithmAuto(t *testing.T) {
+	tests := []struct {
+		priv interface{}
+		want x509.SignatureAlgorithm
+	}{
+		{key.GenerateKey("RSA", 2048), x509.SHA256WithRSA},
+		{key.GenerateKey("P256", 0), x509.ECDSAWithSHA256},
+		{key.GenerateKey("P384", 0), x509.ECDSAWithSHA384},
+		{key.GenerateKey("P521", 0), x509.ECDSAWithSHA512},
+	}
+	for _, test := range tests {
+		if got := signatureAlgorithm("auto", test.priv); got != test.want {
+			t.Fatalf("%T: got: %v, want %v", test.priv, got, test.want)
+		}
+	}
+	if got := signatureAlgorithm("SHA512", key.GenerateKey("P256", 0)); got != x509.ECDSAWithSHA512 {
+		t.Fatalf("got: %v, want explicit ECDSAWithSHA512", got)
+	}
+	for bits, want := range map[int]string{2048: "SHA256", 4096: "SHA256", 7680: "SHA384", 15360: "SHA512"} {
+		if got := recommendedHashForRSA(bits); got != want {
+			t.Fatalf("%d bits: got: %s, want %s", bits, got, want)
+		}
+	}
+}
+
+func TestDNSNamesNormalized(t *testing.T) {
+	template := mustCreateTemplate(t, Certificate{
+		CommonName:       "example.com",
+		AlternativeNames: []string{"Example.COM", " www.example.com. ", "example.com", "WWW.example.com"},
+		PrivateKey:       key.GenerateKey("P256", 0),
+	})
+	want := []string{"example.com", "www.example.com"}
+	if !reflect.DeepEqual(template.DNSNames, want) {
+		t.Fatalf("got: %q, want %q", template.DNSNames, want)
+	}
+}
+
+func TestSerialNumberLimits(t *testing.T) {
+	template := mustCreateTemplate(t, Certificate{Id: "", PrivateKey: key.GenerateKey("P256", 0)})
+	if template.SerialNumber.Sign() <= 0 {
+		t.Fatalf("got serial %v for an empty id, want a random positive serial", template.SerialNumber)
+	}
+	tests := []string{
+		"0",
+		"0x00",
+		strings.Repeat("a", 40),
+		"0x" + strings.Repeat("ff", 20),
+	}
+	for _, id := range tests {
+		if _, err := NewCertificateTemplate(Certificate{Id: id, PrivateKey: key.GenerateKey("P256", 0)}); err == nil {
+			t.Fatalf("%s: expected error", id)
+		}
+	}
+	if _, err := serialNumber("0x7f" + strings.Repeat("ff", 19)); err != nil {
+		t.Fatalf("unexpected error for a 20 octet serial: %v", err)
+	}
+	// an explicit serial is used instead of the id, which is then not checked
+	template = mustCreateTemplate(t, Certificate{Id: strings.Repeat("a", 40), SerialNumber: big.NewInt(42), PrivateKey: key.GenerateKey("P256", 0)})
+	if template.SerialNumber.Int64() != 42 {
+		t.Fatalf("got serial %v, want 42", template.SerialNumber)
+	}
+	if _, err := NewCertificateTemplate(Certificate{SerialNumber: big.NewInt(0), PrivateKey: key.GenerateKey("P256", 0)}); err == nil {
+		t.Fatal("expected error for a zero serial number")
+	}
+}
+
+func TestSerialNumberNegativeAndZero(t *testing.T) {
+	for id, want := range map[string]int64{"-255": 255, "0x-1A": 26} {
+		template := mustCreateTemplate(t, Certificate{Id: id, PrivateKey: key.GenerateKey("P256", 0)})
+		if template.SerialNumber.Int64() != want {
+			t.Fatalf("%s: got serial %v, want %d", id, template.SerialNumber, want)
+		}
+	}
+	for _, id := range []string{"0", "-0", "0x0"} {
+		if _, err := NewCertificateTemplate(Certificate{Id: id, PrivateKey: key.GenerateKey("P256", 0)}); err == nil {
+			t.Fatalf("%s: expected error for a zero serial", id)
+		}
+	}
+}
+
+func TestTimeStampingUsage(t *testing.T) {
+	ca, caPriv := createCA()
+	priv := key.GenerateKey("RSA", 1024)
+	template := mustCreateTemplate(t, Certificate{Id: "tsa", CommonName: "tsa.foo.se", PrivateKey: priv, AllowWeakKey: true, Usage: []string{"signature", "contentcommitment", "timestamping"}})
+	cert, err := x509.ParseCertificate(Sign(template, ca, key.PublicKey(priv), caPriv))
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	var found bool
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidExtKeyUsage) {
+			found = true
+			if !ext.Critical {
+				t.Fatal("extended key usage is not critical")
+			}
+		}
+	}
+	if !found {
+		t.Fatal("extended key usage missing")
+	}
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageTimeStamping || len(cert.UnknownExtKeyUsage) != 0 {
+		t.Fatalf("got: %v %v, want only timestamping", cert.ExtKeyUsage, cert.UnknownExtKeyUsage)
+	}
+	if _, err := NewCertificateTemplate(Certificate{Id: "tsa", PrivateKey: priv, AllowWeakKey: true, Usage: []string{"timestamping", "serverauth"}}); err == nil {
+		t.Fatal("expected error for timestamping combined with another extended key usage")
+	}
+}
+
+func TestUnknownExtKeyUsage(t *testing.T) {
+	ca, caPriv := createCA()
+	priv := key.GenerateKey("RSA", 1024)
+	template := mustCreateTemplate(t, Certificate{Id: "eku", CommonName: "www.foo.se", PrivateKey: priv, AllowWeakKey: true, Usage: []string{"signature", "clientauth"}, UnknownExtKeyUsage: []string{"1.3.6.1.4.1.311.20.2.2"}})
+	cert, err := x509.ParseCertificate(Sign(template, ca, key.PublicKey(priv), caPriv))
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageClientAuth {
+		t.Fatalf("got: %v, want client auth", cert.ExtKeyUsage)
+	}
+	if len(cert.UnknownExtKeyUsage) != 1 || cert.UnknownExtKeyUsage[0].String() != "1.3.6.1.4.1.311.20.2.2" {
+		t.Fatalf("got: %v, want the smart card logon OID", cert.UnknownExtKeyUsage)
+	}
+	if got := FromX509(cert).UnknownExtKeyUsage; len(got) != 1 || got[0] != "1.3.6.1.4.1.311.20.2.2" {
+		t.Fatalf("got: %v, want the OID back", got)
+	}
+	for _, oid := range []string{"1.3.x", "", "1..2"} {
+		if _, err := NewCertificateTemplate(Certificate{Id: "eku", PrivateKey: priv, AllowWeakKey: true, UnknownExtKeyUsage: []string{oid}}); err == nil {
+			t.Fatalf("%q: expected error for an invalid OID", oid)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// RevocationReason is the reason code of a revoked certificate as defined in RFC 5280.
+type RevocationReason int
+
+const (
+	ReasonUnspecified          RevocationReason = 0
+	ReasonKeyCompromise        RevocationReason = 1
+	ReasonCACompromise         RevocationReason = 2
+	ReasonAffiliationChanged   RevocationReason = 3
+	ReasonSuperseded           RevocationReason = 4
+	ReasonCessationOfOperation RevocationReason = 5
+	ReasonCertificateHold      RevocationReason = 6
+	ReasonRemoveFromCRL        RevocationReason = 8
+	ReasonPrivilegeWithdrawn   RevocationReason = 9
+	ReasonAACompromise         RevocationReason = 10
+)
+
+var reasonNames = map[RevocationReason]string{
+	ReasonUnspecified:          "unspecified",
+	ReasonKeyCompromise:        "keyCompromise",
+	ReasonCACompromise:         "cACompromise",
+	ReasonAffiliationChanged:   "affiliationChanged",
+	ReasonSuperseded:           "superseded",
+	ReasonCessationOfOperation: "cessationOfOperation",
+	ReasonCertificateHold:      "certificateHold",
+	ReasonRemoveFromCRL:        "removeFromCRL",
+	ReasonPrivilegeWithdrawn:   "privilegeWithdrawn",
+	ReasonAACompromise:         "aACompromise",
+}
+
+func (r RevocationReason) String() string {
+	if name, ok := reasonNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("RevocationReason(%d)", int(r))
+}
+
+// RevokedEntry is a revoked certificate in a certificate revocation list.
+type RevokedEntry struct {
+	SerialNumber   *big.Int
+	RevocationTime time.Time
+	Reason         RevocationReason
+}
+
+// CreateCRL creates a DER encoded certificate revocation list signed by the issuer,
+// valid from now until validity has passed. number must increase for every new
+// list from the same issuer. The Reason of every entry is written as its reasonCode
+// extension, except for ReasonUnspecified which RFC 5280 wants left out. A certificate
+// on hold is released by leaving it out of the next list, ReasonRemoveFromCRL is only
+// allowed in delta lists.
+func CreateCRL(issuer *x509.Certificate, issuerKey interface{}, revoked []RevokedEntry, number *big.Int, validity time.Duration) ([]byte, error) {
+	return signCRL(issuer, issuerKey, revoked, number, validity, nil)
+}
+
+// CreateDeltaCRL creates a DER encoded delta revocation list with the changes since
+// the complete list with baseNumber. Certificates released from hold since then are
+// listed with ReasonRemoveFromCRL.
+func CreateDeltaCRL(issuer *x509.Certificate, issuerKey interface{}, revoked []RevokedEntry, number, baseNumber *big.Int, validity time.Duration) ([]byte, error) {
+	base, err := asn1.Marshal(baseNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode base CRL number: %v", err)
+	}
+	indicator := pkix.Extension{Id: oidDeltaCRLIndicator, Critical: true, Value: base}
+	return signCRL(issuer, issuerKey, revoked, number, validity, &indicator)
+}
+
+var oidDeltaCRLIndicator = asn1.ObjectIdentifier{2, 5, 29, 27}
+
+// signCRL creates a complete list, or a delta list if deltaIndicator is set.
+func signCRL(issuer *x509.Certificate, issuerKey interface{}, revoked []RevokedEntry, number *big.Int, validity time.Duration, deltaIndicator *pkix.Extension) ([]byte, error) {
+	signer, ok := issuerKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported issuer key type: %T", issuerKey)
+	}
+	entries := make([]x509.RevocationListEntry, 0, len(revoked))
+	for _, r := range revoked {
+		if _, ok := reasonNames[r.Reason]; !ok {
+			return nil, fmt.Errorf("serial %v has unknown revocation reason %d", r.SerialNumber, int(r.Reason))
+		}
+		if r.Reason == ReasonRemoveFromCRL && deltaIndicator == nil {
+			return nil, fmt.Errorf("serial %v has reason %v which is only allowed in delta CRLs", r.SerialNumber, r.Reason)
+		}
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   r.SerialNumber,
+			RevocationTime: r.RevocationTime,
+			ReasonCode:     int(r.Reason),
+		})
+	}
+	now := time.Now()
+	template := &x509.RevocationList{
+		Number:                    number,
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(validity),
+		RevokedCertificateEntries: entries,
+	}
+	if deltaIndicator != nil {
+		template.ExtraExtensions = []pkix.Extension{*deltaIndicator}
+	}
+	crl, err := x509.CreateRevocationList(RandReader, template, issuer, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CRL: %v", err)
+	}
+	return crl, nil
+}
+
+// ParseCRL parses a PEM or DER encoded certificate revocation list. The signature is
+// not checked, use VerifyCRLSignature for that.
+func ParseCRL(pemOrDER []byte) (*x509.RevocationList, error) {
+	der := pemOrDER
+	if isPem(pemOrDER) {
+		block, _ := pem.Decode(pemOrDER)
+		if block == nil || block.Type != "X509 CRL" {
+			return nil, errors.New("failed to parse CRL: no X509 CRL PEM block found")
+		}
+		der = block.Bytes
+	}
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CRL: %v", err)
+	}
+	return crl, nil
+}
+
+// VerifyCRLSignature checks that crl is signed by issuer.
+func VerifyCRLSignature(crl *x509.RevocationList, issuer *x509.Certificate) error {
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return fmt.Errorf("CRL is not signed by the issuer %v: %v", issuer.Subject, err)
+	}
+	return nil
+}
+
+// ListRevoked returns the revoked certificates in crl with their reason codes.
+func ListRevoked(crl *x509.RevocationList) []RevokedEntry {
+	revoked := make([]RevokedEntry, 0, len(crl.RevokedCertificateEntries))
+	for _, r := range crl.RevokedCertificateEntries {
+		revoked = append(revoked, RevokedEntry{
+			SerialNumber:   r.SerialNumber,
+			RevocationTime: r.RevocationTime,
+			Reason:         RevocationReason(r.ReasonCode),
+		})
+	}
+	return revoked
+}
+
+// IsRevoked tells if the certificate with serial is in crl. Entries of delta lists
+// with ReasonRemoveFromCRL do not count, those certificates are no longer on hold.
+func IsRevoked(crl *x509.RevocationList, serial *big.Int) bool {
+	for _, r := range crl.RevokedCertificateEntries {
+		if r.SerialNumber.Cmp(serial) == 0 && RevocationReason(r.ReasonCode) != ReasonRemoveFromCRL {
+			return true
+		}
+	}
+	return false
+}
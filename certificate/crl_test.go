@@ -0,0 +1,137 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestParseCRL(t *testing.T) {
+	issuer := testIssuer(t)
+	revokedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	der, err := x509.CreateRevocationList(RandReader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{
+			{SerialNumber: big.NewInt(7), RevocationTime: revokedAt, ReasonCode: int(ReasonKeyCompromise)},
+			{SerialNumber: big.NewInt(9), RevocationTime: revokedAt},
+		},
+	}, issuer.Cert, issuer.Key.(crypto.Signer))
+	if err != nil {
+		t.Fatalf("failed to create CRL: %v", err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+	for name, data := range map[string][]byte{"der": der, "pem": pemBytes} {
+		crl, err := ParseCRL(data)
+		if err != nil {
+			t.Fatalf("%s: failed to parse CRL: %v", name, err)
+		}
+		revoked := ListRevoked(crl)
+		if len(revoked) != 2 {
+			t.Fatalf("%s: got %d entries, want 2", name, len(revoked))
+		}
+		if revoked[0].SerialNumber.Int64() != 7 || !revoked[0].RevocationTime.Equal(revokedAt) || revoked[0].Reason != ReasonKeyCompromise {
+			t.Errorf("%s: got: %+v, want serial 7 revoked for keyCompromise", name, revoked[0])
+		}
+		if revoked[1].Reason != ReasonUnspecified {
+			t.Errorf("%s: got reason %v, want unspecified", name, revoked[1].Reason)
+		}
+		if !IsRevoked(crl, big.NewInt(9)) || IsRevoked(crl, big.NewInt(8)) {
+			t.Errorf("%s: IsRevoked does not match the entries", name)
+		}
+	}
+	if _, err := ParseCRL([]byte("-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n")); err == nil {
+		t.Error("expected a PEM block of the wrong type to fail")
+	}
+}
+
+func TestVerifyCRLSignature(t *testing.T) {
+	issuer := testIssuer(t)
+	der, err := CreateCRL(issuer.Cert, issuer.Key, nil, big.NewInt(1), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create CRL: %v", err)
+	}
+	crl, err := ParseCRL(der)
+	if err != nil {
+		t.Fatalf("failed to parse CRL: %v", err)
+	}
+	if err := VerifyCRLSignature(crl, issuer.Cert); err != nil {
+		t.Errorf("failed to verify CRL: %v", err)
+	}
+	if err := VerifyCRLSignature(crl, testIssuer(t).Cert); err == nil {
+		t.Error("expected a CRL from another issuer to fail verification")
+	}
+}
+
+func TestRevocationReasonString(t *testing.T) {
+	if got := ReasonCessationOfOperation.String(); got != "cessationOfOperation" {
+		t.Errorf("got: %s, want cessationOfOperation", got)
+	}
+	if got := RevocationReason(7).String(); got != "RevocationReason(7)" {
+		t.Errorf("got: %s, want RevocationReason(7)", got)
+	}
+}
+
+func TestCreateCRLReasons(t *testing.T) {
+	issuer := testIssuer(t)
+	now := time.Now().Truncate(time.Second)
+	var revoked []RevokedEntry
+	for i, reason := range []RevocationReason{ReasonUnspecified, ReasonKeyCompromise, ReasonCessationOfOperation, ReasonSuperseded, ReasonCertificateHold} {
+		revoked = append(revoked, RevokedEntry{SerialNumber: big.NewInt(int64(i + 1)), RevocationTime: now, Reason: reason})
+	}
+	der, err := CreateCRL(issuer.Cert, issuer.Key, revoked, big.NewInt(1), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create CRL: %v", err)
+	}
+	crl, err := ParseCRL(der)
+	if err != nil {
+		t.Fatalf("failed to parse CRL: %v", err)
+	}
+	got := ListRevoked(crl)
+	if len(got) != len(revoked) {
+		t.Fatalf("got %d entries, want %d", len(got), len(revoked))
+	}
+	for i := range revoked {
+		if got[i].Reason != revoked[i].Reason {
+			t.Errorf("serial %v: got reason %v, want %v", got[i].SerialNumber, got[i].Reason, revoked[i].Reason)
+		}
+	}
+	for _, reason := range []RevocationReason{ReasonRemoveFromCRL, RevocationReason(7)} {
+		revoked := []RevokedEntry{{SerialNumber: big.NewInt(1), RevocationTime: now, Reason: reason}}
+		if _, err := CreateCRL(issuer.Cert, issuer.Key, revoked, big.NewInt(2), time.Hour); err == nil {
+			t.Errorf("%v: expected complete CRL to be rejected", reason)
+		}
+	}
+}
+
+func TestCreateDeltaCRLReleasesHold(t *testing.T) {
+	issuer := testIssuer(t)
+	released := []RevokedEntry{{SerialNumber: big.NewInt(5), RevocationTime: time.Now(), Reason: ReasonRemoveFromCRL}}
+	der, err := CreateDeltaCRL(issuer.Cert, issuer.Key, released, big.NewInt(3), big.NewInt(2), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create delta CRL: %v", err)
+	}
+	crl, err := ParseCRL(der)
+	if err != nil {
+		t.Fatalf("failed to parse delta CRL: %v", err)
+	}
+	var delta bool
+	for _, ext := range crl.Extensions {
+		if ext.Id.Equal(oidDeltaCRLIndicator) && ext.Critical {
+			delta = true
+		}
+	}
+	if !delta {
+		t.Error("delta CRL has no critical delta CRL indicator")
+	}
+	if got := ListRevoked(crl); len(got) != 1 || got[0].Reason != ReasonRemoveFromCRL {
+		t.Errorf("got: %+v, want serial 5 removed from CRL", got)
+	}
+	if IsRevoked(crl, big.NewInt(5)) {
+		t.Error("expected a certificate removed from the CRL not to be revoked")
+	}
+}
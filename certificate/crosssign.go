@@ -0,0 +1,72 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// CrossSign issues a second certificate for an existing CA under a new parent, so clients
+// trusting either parent can build a chain. The subject, subject key id, basic constraints,
+// key usage and public key are copied from the existing CA certificate.
+func CrossSign(existingCADER []byte, newParentCert *x509.Certificate, newParentKey interface{}, validity time.Duration) ([]byte, error) {
+	existing, err := x509.ParseCertificate(existingCADER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate to cross sign: %v", err)
+	}
+	if !existing.IsCA {
+		return nil, errors.New("certificate to cross sign is not a CA")
+	}
+	if validity <= 0 {
+		return nil, fmt.Errorf("validity must be positive, got: %v", validity)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	notBefore := time.Now().Add(-defaultNotBeforeSkew)
+	cert := crossSignTemplate(existing, serial, notBefore, notBefore.Add(validity))
+	return SignCertificate(cert, newParentCert, existing.PublicKey, newParentKey)
+}
+
+// CrossSignNewCA is used for CA key rotation: it issues a certificate with the subject and
+// key of newCA signed by oldCA, so that certificates issued by the new CA keep verifying
+// for clients that only trust the old root. The validity of newCA is kept, but clamped
+// to the validity of oldCA.
+func CrossSignNewCA(newCA *x509.Certificate, newCAPub interface{}, oldCA *x509.Certificate, oldCAKey interface{}) ([]byte, error) {
+	if !newCA.IsCA {
+		return nil, errors.New("new certificate to cross sign is not a CA")
+	}
+	if newCA.PublicKey != nil && !publicKeysEqual(newCA.PublicKey, newCAPub) {
+		return nil, errors.New("public key does not belong to the new CA certificate")
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	cert := crossSignTemplate(newCA, serial, newCA.NotBefore, newCA.NotAfter)
+	if err := applySignerValidity(cert, oldCA, ClampToSigner); err != nil {
+		return nil, err
+	}
+	return SignCertificate(cert, oldCA, newCAPub, oldCAKey)
+}
+
+// crossSignTemplate copies the identity of the CA certificate existing into a new template.
+func crossSignTemplate(existing *x509.Certificate, serial *big.Int, notBefore, notAfter time.Time) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber:          serial,
+		RawSubject:            existing.RawSubject,
+		Subject:               existing.Subject,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		SubjectKeyId:          existing.SubjectKeyId,
+		BasicConstraintsValid: existing.BasicConstraintsValid,
+		IsCA:                  existing.IsCA,
+		MaxPathLen:            existing.MaxPathLen,
+		MaxPathLenZero:        existing.MaxPathLenZero,
+		KeyUsage:              existing.KeyUsage,
+		ExtKeyUsage:           existing.ExtKeyUsage,
+	}
+}
@@ -0,0 +1,72 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestCrossSign(t *testing.T) {
+	oldRoot, oldRootPriv := createCA()
+	oldRootBytes := Sign(oldRoot, oldRoot, key.PublicKey(oldRootPriv), oldRootPriv)
+	newRootPriv := key.GenerateKey("P256", 0)
+	newRoot := mustCreateTemplate(t, Certificate{Id: "root2", OrganizationalUnit: "NewCA", CA: true, PrivateKey: newRootPriv, AllowWeakKey: true})
+	newRootBytes := Sign(newRoot, newRoot, key.PublicKey(newRootPriv), newRootPriv)
+
+	interCa, interCaPriv := createInterCA()
+	interCaBytes := Sign(interCa, oldRoot, key.PublicKey(interCaPriv), oldRootPriv)
+	crossBytes, err := CrossSign(interCaBytes, newRoot, newRootPriv, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to cross sign: %v", err)
+	}
+	client, clientPriv := createClient()
+	clientBytes := Sign(client, interCa, key.PublicKey(clientPriv), interCaPriv)
+
+	if !CheckCertificate("www.baz.se", oldRootBytes, interCaBytes, clientBytes) {
+		t.Fatal("chain through the old root failed to verify")
+	}
+	if !CheckCertificate("www.baz.se", newRootBytes, crossBytes, clientBytes) {
+		t.Fatal("chain through the cross signed certificate failed to verify")
+	}
+}
+
+func TestCrossSignLeaf(t *testing.T) {
+	ca, caPriv := createCA()
+	client, clientPriv := createClient()
+	clientBytes := Sign(client, ca, key.PublicKey(clientPriv), caPriv)
+	if _, err := CrossSign(clientBytes, ca, caPriv, time.Hour); err == nil {
+		t.Fatal("expected error then cross signing a leaf certificate")
+	}
+}
+
+func TestCrossSignNewCA(t *testing.T) {
+	from := time.Now().Truncate(time.Second).Add(-time.Hour)
+	oldRootPriv := key.GenerateKey("RSA", 1024)
+	oldRoot := mustCreateTemplate(t, Certificate{Id: "root1", CommonName: "root", OrganizationalUnit: "OldCA", CA: true, PrivateKey: oldRootPriv, AllowWeakKey: true, ValidFrom: from, ValidTo: from.AddDate(1, 0, 0)})
+	oldRootBytes := Sign(oldRoot, oldRoot, key.PublicKey(oldRootPriv), oldRootPriv)
+	newRootPriv := key.GenerateKey("RSA", 1024)
+	newRoot := mustCreateTemplate(t, Certificate{Id: "root2", CommonName: "root", OrganizationalUnit: "NewCA", CA: true, PrivateKey: newRootPriv, AllowWeakKey: true, ValidFrom: from, ValidTo: from.AddDate(5, 0, 0)})
+	newRootBytes := Sign(newRoot, newRoot, key.PublicKey(newRootPriv), newRootPriv)
+	newRootCert, _ := x509.ParseCertificate(newRootBytes)
+
+	crossBytes, err := CrossSignNewCA(newRootCert, key.PublicKey(newRootPriv), oldRoot, oldRootPriv)
+	if err != nil {
+		t.Fatalf("failed to cross sign: %v", err)
+	}
+	cross, _ := x509.ParseCertificate(crossBytes)
+	if !bytes.Equal(cross.RawSubject, newRootCert.RawSubject) || !cross.NotAfter.Equal(oldRoot.NotAfter) {
+		t.Fatalf("got subject %v valid to %v, want the new CA subject valid to %v", cross.Subject, cross.NotAfter, oldRoot.NotAfter)
+	}
+
+	client, clientPriv := createClient()
+	clientBytes := Sign(client, newRootCert, key.PublicKey(clientPriv), newRootPriv)
+	if !CheckCertificate("www.baz.se", oldRootBytes, crossBytes, clientBytes) {
+		t.Fatal("leaf of the new CA failed to verify through the cross signed certificate against the old root")
+	}
+	if _, err := CrossSignNewCA(newRootCert, key.PublicKey(oldRootPriv), oldRoot, oldRootPriv); err == nil {
+		t.Fatal("expected error for a public key of another CA")
+	}
+}
@@ -0,0 +1,65 @@
+package certificate
+
+import (
+	"encoding/pem"
+	"net"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+var defaultDevHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// DevCertificate creates a throwaway ECDSA P-256 CA and a certificate signed by it for
+// local development. The hosts can be DNS names or IP addresses, localhost, 127.0.0.1
+// and ::1 are used if no host is given. The CA is returned so it can be added to a
+// trust store or to the RootCAs of a http.Client.
+func DevCertificate(hosts ...string) (caPEM, certPEM, keyPEM []byte, err error) {
+	if len(hosts) == 0 {
+		hosts = defaultDevHosts
+	}
+	now := time.Now()
+	ca, err := NewCA(Certificate{
+		Organization:       "certificateBar development CA",
+		OrganizationalUnit: "development",
+		CommonName:         "certificateBar development CA",
+		CA:                 true,
+		MaxPathLenZero:     true,
+		PrivateKey:         key.GenerateKey("P256", 0),
+		ValidFrom:          now.Add(-defaultNotBeforeSkew),
+		ValidTo:            now.AddDate(1, 0, 0),
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	leaf := Certificate{
+		Organization: "certificateBar development",
+		Usage:        []string{"signature", "encipherment", "serverauth", "clientauth"},
+		PrivateKey:   key.GenerateKey("P256", 0),
+		ValidFrom:    now.Add(-defaultNotBeforeSkew),
+		ValidTo:      now.AddDate(1, 0, 0),
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			leaf.IPAddresses = append(leaf.IPAddresses, ip)
+		} else {
+			leaf.AlternativeNames = append(leaf.AlternativeNames, host)
+		}
+	}
+	if len(leaf.AlternativeNames) > 0 {
+		leaf.CommonName = leaf.AlternativeNames[0]
+	}
+	der, err := ca.Issue(leaf)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	keyPEM, err = key.EncodePrivateKeyPem(leaf.PrivateKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return encodeCertificatePem(ca.Cert.Raw), encodeCertificatePem(der), keyPEM, nil
+}
+
+func encodeCertificatePem(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
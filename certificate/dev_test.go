@@ -0,0 +1,53 @@
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/tls"
+	"testing"
+	"time"
+)
+
+func TestDevCertificateDefaults(t *testing.T) {
+	caPEM, certPEM, keyPEM, err := DevCertificate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		t.Fatalf("certificate and key do not match: %v", err)
+	}
+	ca, _ := parseCertificatePem(caPEM)
+	cert, _ := parseCertificatePem(certPEM)
+	for _, host := range []string{"localhost", "127.0.0.1", "::1"} {
+		if !CheckCertificate(host, ca.Raw, nil, cert.Raw) {
+			t.Fatalf("certificate not valid for %s", host)
+		}
+	}
+	if len(cert.DNSNames) != 1 || len(cert.IPAddresses) != 2 {
+		t.Fatalf("got DNS names: %v and IPs: %v", cert.DNSNames, cert.IPAddresses)
+	}
+	if pub, ok := cert.PublicKey.(*ecdsa.PublicKey); !ok || pub.Curve != elliptic.P256() {
+		t.Fatal("certificate key is not ECDSA P-256")
+	}
+	if cert.NotAfter.After(time.Now().AddDate(1, 0, 1)) {
+		t.Fatalf("certificate valid too long: %v", cert.NotAfter)
+	}
+}
+
+func TestDevCertificateHosts(t *testing.T) {
+	caPEM, certPEM, _, err := DevCertificate("10.0.0.1", "dev.foo.se")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ca, _ := parseCertificatePem(caPEM)
+	cert, _ := parseCertificatePem(certPEM)
+	if cert.Subject.CommonName != "dev.foo.se" {
+		t.Fatalf("got: %v, want dev.foo.se", cert.Subject.CommonName)
+	}
+	if !CheckCertificate("10.0.0.1", ca.Raw, nil, cert.Raw) || !CheckCertificate("dev.foo.se", ca.Raw, nil, cert.Raw) {
+		t.Fatal("certificate not valid for the given hosts")
+	}
+	if CheckCertificate("localhost", ca.Raw, nil, cert.Raw) {
+		t.Fatal("certificate valid for localhost")
+	}
+}
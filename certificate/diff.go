@@ -0,0 +1,32 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DiffCertificates compares two certificates, e.g. before and after a rotation, and
+// returns a human readable line for every field that changed: subject, subject
+// alternative names, key usage, extended key usage, validity, serial number and
+// public key. Unchanged fields are left out, an empty result means they are equal.
+func DiffCertificates(old, new *x509.Certificate) []string {
+	var diffs []string
+	changed := func(field, from, to string) {
+		if from != to {
+			diffs = append(diffs, fmt.Sprintf("%s: %s -> %s", field, from, to))
+		}
+	}
+	changed("subject", old.Subject.String(), new.Subject.String())
+	changed("SANs", strings.Join(AllSANs(old), ", "), strings.Join(AllSANs(new), ", "))
+	changed("key usage", strings.Join(keyUsageList(old.KeyUsage), ", "), strings.Join(keyUsageList(new.KeyUsage), ", "))
+	changed("extended key usage", strings.Join(extKeyUsageList(old), ", "), strings.Join(extKeyUsageList(new), ", "))
+	changed("not before", old.NotBefore.UTC().Format(time.RFC3339), new.NotBefore.UTC().Format(time.RFC3339))
+	changed("not after", old.NotAfter.UTC().Format(time.RFC3339), new.NotAfter.UTC().Format(time.RFC3339))
+	changed("serial", colonHex(old.SerialNumber.Bytes()), colonHex(new.SerialNumber.Bytes()))
+	if !publicKeysEqual(old.PublicKey, new.PublicKey) {
+		diffs = append(diffs, fmt.Sprintf("public key: %s -> %s", publicKeyDescription(old.PublicKey), publicKeyDescription(new.PublicKey)))
+	}
+	return diffs
+}
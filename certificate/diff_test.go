@@ -0,0 +1,36 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestDiffCertificates(t *testing.T) {
+	issuer := testIssuer(t)
+	priv := key.GenerateKey("P256", 0)
+	now := time.Now()
+	data := Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: priv, ValidFrom: now, ValidTo: now.AddDate(0, 1, 0)}
+	oldDER, _ := issuer.Issue(data)
+	data.ValidTo = now.AddDate(0, 2, 0)
+	newDER, _ := issuer.Issue(data)
+	old, _ := x509.ParseCertificate(oldDER)
+	renewed, _ := x509.ParseCertificate(newDER)
+
+	diffs := DiffCertificates(old, renewed)
+	if len(diffs) != 2 || !strings.HasPrefix(diffs[0], "not after: ") || !strings.HasPrefix(diffs[1], "serial: ") {
+		t.Fatalf("got: %q, want not after and serial", diffs)
+	}
+	if diffs := DiffCertificates(old, old); len(diffs) != 0 {
+		t.Fatalf("got: %q, want no differences", diffs)
+	}
+	data.PrivateKey = key.GenerateKey("P384", 0)
+	otherDER, _ := issuer.Issue(data)
+	other, _ := x509.ParseCertificate(otherDER)
+	if diffs := DiffCertificates(renewed, other); !strings.HasPrefix(diffs[len(diffs)-1], "public key: ECDSA P-256") {
+		t.Fatalf("got: %q, want changed public key", diffs)
+	}
+}
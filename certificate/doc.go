@@ -0,0 +1,10 @@
+// Package certificate creates, signs and verifies X.509 certificates for test setups.
+//
+// Concurrency: CreateCertificateTemplate, Sign and the other functions keep no shared
+// state and may be called from several goroutines, each call uses its own hashers.
+// Issuer and CA are safe for concurrent use. The package variables RandReader and
+// UnsafeSkipSignerChecks, and the logger set with SetLogger, must only be changed
+// before certificates are created.
+//
+// Logging: the package writes nothing until a Logger is set with SetLogger.
+package certificate
@@ -0,0 +1,134 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var keyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "Digital Signature"},
+	{x509.KeyUsageContentCommitment, "Content Commitment"},
+	{x509.KeyUsageKeyEncipherment, "Key Encipherment"},
+	{x509.KeyUsageDataEncipherment, "Data Encipherment"},
+	{x509.KeyUsageKeyAgreement, "Key Agreement"},
+	{x509.KeyUsageCertSign, "Certificate Sign"},
+	{x509.KeyUsageCRLSign, "CRL Sign"},
+	{x509.KeyUsageEncipherOnly, "Encipher Only"},
+	{x509.KeyUsageDecipherOnly, "Decipher Only"},
+}
+
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:                            "Any Extended Key Usage",
+	x509.ExtKeyUsageServerAuth:                     "TLS Web Server Authentication",
+	x509.ExtKeyUsageClientAuth:                     "TLS Web Client Authentication",
+	x509.ExtKeyUsageCodeSigning:                    "Code Signing",
+	x509.ExtKeyUsageEmailProtection:                "E-mail Protection",
+	x509.ExtKeyUsageIPSECEndSystem:                 "IPSec End System",
+	x509.ExtKeyUsageIPSECTunnel:                    "IPSec Tunnel",
+	x509.ExtKeyUsageIPSECUser:                      "IPSec User",
+	x509.ExtKeyUsageTimeStamping:                   "Time Stamping",
+	x509.ExtKeyUsageOCSPSigning:                    "OCSP Signing",
+	x509.ExtKeyUsageMicrosoftServerGatedCrypto:     "Microsoft Server Gated Crypto",
+	x509.ExtKeyUsageNetscapeServerGatedCrypto:      "Netscape Server Gated Crypto",
+	x509.ExtKeyUsageMicrosoftCommercialCodeSigning: "Microsoft Commercial Code Signing",
+	x509.ExtKeyUsageMicrosoftKernelCodeSigning:     "Microsoft Kernel Code Signing",
+}
+
+// dumpWriter keeps the first error so DumpText does not need to check every write.
+type dumpWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (d *dumpWriter) printf(format string, args ...interface{}) {
+	if d.err != nil {
+		return
+	}
+	_, d.err = fmt.Fprintf(d.w, format, args...)
+}
+
+// DumpText writes a human readable description of the certificate, the equivalent
+// of `openssl x509 -text`. The fields are always written in the same order so
+// dumps can be compared.
+func DumpText(cert *x509.Certificate, w io.Writer) error {
+	d := &dumpWriter{w: w}
+	d.printf("Certificate:\n")
+	d.printf("    Version: %d\n", cert.Version)
+	d.printf("    Serial Number: %s\n", colonHex(cert.SerialNumber.Bytes()))
+	d.printf("    Signature Algorithm: %v\n", cert.SignatureAlgorithm)
+	d.printf("    Issuer: %v\n", cert.Issuer)
+	d.printf("    Validity:\n")
+	d.printf("        Not Before: %s\n", cert.NotBefore.UTC().Format(time.RFC3339))
+	d.printf("        Not After : %s\n", cert.NotAfter.UTC().Format(time.RFC3339))
+	d.printf("    Subject: %v\n", cert.Subject)
+	d.printf("    Public Key: %s\n", publicKeyDescription(cert.PublicKey))
+	d.printf("    Subject Alternative Names: %s\n", strings.Join(AllSANs(cert), ", "))
+	d.printf("    Key Usage: %s\n", strings.Join(keyUsageList(cert.KeyUsage), ", "))
+	d.printf("    Extended Key Usage: %s\n", strings.Join(extKeyUsageList(cert), ", "))
+	if cert.BasicConstraintsValid {
+		d.printf("    Basic Constraints: CA:%v%s\n", cert.IsCA, pathLenDescription(cert))
+	} else {
+		d.printf("    Basic Constraints:\n")
+	}
+	d.printf("    Subject Key Identifier: %s\n", colonHex(cert.SubjectKeyId))
+	d.printf("    Authority Key Identifier: %s\n", colonHex(cert.AuthorityKeyId))
+	sha1Fingerprint, _ := Fingerprint(cert.Raw, crypto.SHA1)
+	d.printf("    SHA1 Fingerprint: %s\n", sha1Fingerprint)
+	d.printf("    SHA256 Fingerprint: %s\n", FingerprintSHA256(cert))
+	return d.err
+}
+
+func publicKeyDescription(pub interface{}) string {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA (%d bit)", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA %s (%d bit)", k.Curve.Params().Name, k.Curve.Params().BitSize)
+	case ed25519.PublicKey:
+		return "Ed25519 (256 bit)"
+	default:
+		return fmt.Sprintf("unknown (%T)", pub)
+	}
+}
+
+func keyUsageList(usage x509.KeyUsage) []string {
+	var names []string
+	for _, u := range keyUsageNames {
+		if usage&u.usage != 0 {
+			names = append(names, u.name)
+		}
+	}
+	return names
+}
+
+func extKeyUsageList(cert *x509.Certificate) []string {
+	var names []string
+	for _, u := range cert.ExtKeyUsage {
+		if name, ok := extKeyUsageNames[u]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, fmt.Sprintf("unknown (%d)", u))
+		}
+	}
+	for _, oid := range cert.UnknownExtKeyUsage {
+		names = append(names, oid.String())
+	}
+	return names
+}
+
+func pathLenDescription(cert *x509.Certificate) string {
+	if !cert.IsCA || (cert.MaxPathLen <= 0 && !cert.MaxPathLenZero) {
+		return ""
+	}
+	return fmt.Sprintf(", pathlen:%d", cert.MaxPathLen)
+}
@@ -0,0 +1,65 @@
+package certificate
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var opensslCertDump = `Certificate:
+    Version: 3
+    Serial Number: 18:EE:29:94:1E:A7:66:93:9A:F1:7B:77:97:C3:17:A5:54:EB:8E:26
+    Signature Algorithm: ECDSA-SHA256
+    Issuer: CN=www.foo.se,O=test,C=SE
+    Validity:
+        Not Before: 2026-10-16T07:56:43Z
+        Not After : 2036-10-13T07:56:43Z
+    Subject: CN=www.foo.se,O=test,C=SE
+    Public Key: ECDSA P-256 (256 bit)
+    Subject Alternative Names: 
+    Key Usage: 
+    Extended Key Usage: 
+    Basic Constraints: CA:true
+    Subject Key Identifier: 9F:E8:13:68:6C:9F:41:1B:FC:5A:95:99:18:6B:A5:B6:5F:4F:97:E2
+    Authority Key Identifier: 9F:E8:13:68:6C:9F:41:1B:FC:5A:95:99:18:6B:A5:B6:5F:4F:97:E2
+    SHA1 Fingerprint: B6:ED:9E:11:5B:08:90:5A:E6:BD:69:F8:88:85:BC:D1:A9:04:93:AB
+    SHA256 Fingerprint: 00:A0:04:95:CC:DD:1A:86:8D:CF:67:94:32:CA:BC:F9:CC:EB:BC:3B:9A:72:26:4B:1B:67:D5:96:3B:FC:42:D9
+`
+
+func TestDumpText(t *testing.T) {
+	cert, _ := parseCertificatePem([]byte(opensslCertPem))
+	var buf bytes.Buffer
+	if err := DumpText(cert, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != opensslCertDump {
+		t.Fatalf("got:\n%s\nwant:\n%s", buf.String(), opensslCertDump)
+	}
+}
+
+func TestDumpTextAllFields(t *testing.T) {
+	client, _ := createClient()
+	client.DNSNames = []string{"www.foo.se"}
+	var buf bytes.Buffer
+	if err := DumpText(client, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"DNS:www.foo.se", "Digital Signature, Key Encipherment", "TLS Web Client Authentication, TLS Web Server Authentication", "CA:false"} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Fatalf("missing %q in:\n%s", want, buf.String())
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestDumpTextWriteError(t *testing.T) {
+	cert, _ := parseCertificatePem([]byte(opensslCertPem))
+	if err := DumpText(cert, failingWriter{}); err == nil {
+		t.Fatal("expected write error")
+	}
+}
@@ -0,0 +1,110 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"time"
+)
+
+// ValidityState tells if a certificate is valid at a given time.
+type ValidityState int
+
+const (
+	Valid ValidityState = iota
+	NotYetValid
+	Expired
+)
+
+func (s ValidityState) String() string {
+	switch s {
+	case NotYetValid:
+		return "not yet valid"
+	case Expired:
+		return "expired"
+	default:
+		return "valid"
+	}
+}
+
+// State returns the validity state of the certificate at the given time. A certificate
+// with NotBefore in the future is not yet valid, which is not the same as expired.
+func State(cert *x509.Certificate, at time.Time) ValidityState {
+	switch {
+	case at.Before(cert.NotBefore):
+		return NotYetValid
+	case at.After(cert.NotAfter):
+		return Expired
+	default:
+		return Valid
+	}
+}
+
+// IsExpired returns true if the certificate has expired at the given time.
+func IsExpired(cert *x509.Certificate, at time.Time) bool {
+	return State(cert, at) == Expired
+}
+
+// IsNotYetValid returns true if the certificate is not yet valid at the given time.
+func IsNotYetValid(cert *x509.Certificate, at time.Time) bool {
+	return State(cert, at) == NotYetValid
+}
+
+// ExpiresWithin returns true if the certificate expires within d from now,
+// already expired certificates are included.
+func ExpiresWithin(cert *x509.Certificate, d time.Duration) bool {
+	return IsExpired(cert, time.Now().Add(d))
+}
+
+// RemainingValidity returns the time left until the certificate expires, zero if it has expired.
+func RemainingValidity(cert *x509.Certificate) time.Duration {
+	remaining := time.Until(cert.NotAfter)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// StatePem is State for a PEM encoded certificate, as written by WritePemToFile.
+func StatePem(pemBytes []byte, at time.Time) (ValidityState, error) {
+	cert, err := parseCertificatePem(pemBytes)
+	if err != nil {
+		return Valid, err
+	}
+	return State(cert, at), nil
+}
+
+// IsExpiredPem is IsExpired for a PEM encoded certificate.
+func IsExpiredPem(pemBytes []byte, at time.Time) (bool, error) {
+	cert, err := parseCertificatePem(pemBytes)
+	if err != nil {
+		return false, err
+	}
+	return IsExpired(cert, at), nil
+}
+
+// ExpiresWithinPem is ExpiresWithin for a PEM encoded certificate.
+func ExpiresWithinPem(pemBytes []byte, d time.Duration) (bool, error) {
+	cert, err := parseCertificatePem(pemBytes)
+	if err != nil {
+		return false, err
+	}
+	return ExpiresWithin(cert, d), nil
+}
+
+// RemainingValidityPem is RemainingValidity for a PEM encoded certificate.
+func RemainingValidityPem(pemBytes []byte) (time.Duration, error) {
+	cert, err := parseCertificatePem(pemBytes)
+	if err != nil {
+		return 0, err
+	}
+	return RemainingValidity(cert), nil
+}
+
+func parseCertificatePem(pemBytes []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
@@ -0,0 +1,71 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func createSignedCert(t *testing.T, from, to time.Time) *x509.Certificate {
+	priv := key.GenerateKey("P256", 0)
+	template := mustCreateTemplate(t, Certificate{Id: "one", CommonName: "www.foo.se", PrivateKey: priv, ValidFrom: from, ValidTo: to})
+	cert, err := x509.ParseCertificate(Sign(template, template, key.PublicKey(priv), priv))
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+func TestState(t *testing.T) {
+	now := time.Now()
+	cert := createSignedCert(t, now.Add(-time.Hour), now.Add(time.Hour))
+	if s := State(cert, now); s != Valid {
+		t.Fatalf("got: %v, want %v", s, Valid)
+	}
+	if s := State(cert, now.Add(2*time.Hour)); s != Expired {
+		t.Fatalf("got: %v, want %v", s, Expired)
+	}
+	if s := State(cert, now.Add(-2*time.Hour)); s != NotYetValid {
+		t.Fatalf("got: %v, want %v", s, NotYetValid)
+	}
+	if IsExpired(cert, now.Add(-2*time.Hour)) {
+		t.Fatal("not yet valid certificate reported as expired")
+	}
+	if !IsNotYetValid(cert, now.Add(-2*time.Hour)) {
+		t.Fatal("not yet valid certificate not reported")
+	}
+}
+
+func TestExpiresWithin(t *testing.T) {
+	now := time.Now()
+	cert := createSignedCert(t, now.Add(-time.Hour), now.Add(24*time.Hour))
+	if ExpiresWithin(cert, time.Hour) {
+		t.Fatal("certificate should not expire within one hour")
+	}
+	if !ExpiresWithin(cert, 48*time.Hour) {
+		t.Fatal("certificate should expire within two days")
+	}
+	if r := RemainingValidity(cert); r <= 23*time.Hour || r > 24*time.Hour {
+		t.Fatalf("got: %v, want about 24h", r)
+	}
+	expired := createSignedCert(t, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	if r := RemainingValidity(expired); r != 0 {
+		t.Fatalf("got: %v, want 0", r)
+	}
+}
+
+func TestExpiryPem(t *testing.T) {
+	now := time.Now()
+	cert := createSignedCert(t, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	expired, err := IsExpiredPem(pemBytes, now)
+	if err != nil || !expired {
+		t.Fatalf("got: %v %v, want true", expired, err)
+	}
+	if _, err := IsExpiredPem([]byte("garbage"), now); err == nil {
+		t.Fatal("expected error for invalid PEM")
+	}
+}
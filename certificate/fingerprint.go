@@ -0,0 +1,50 @@
+package certificate
+
+import (
+	"crypto"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+// Fingerprint returns the fingerprint of a DER or PEM encoded certificate in the same
+// colon separated uppercase hex form as `openssl x509 -fingerprint` prints.
+func Fingerprint(der []byte, hash crypto.Hash) (string, error) {
+	if !hash.Available() {
+		return "", fmt.Errorf("hash function %v is not available", hash)
+	}
+	if block, _ := pem.Decode(der); block != nil {
+		if block.Type != "CERTIFICATE" {
+			return "", fmt.Errorf("expected CERTIFICATE PEM block, got: %s", block.Type)
+		}
+		der = block.Bytes
+	}
+	h := hash.New()
+	h.Write(der)
+	return colonHex(h.Sum(nil)), nil
+}
+
+// FingerprintSHA256 returns the SHA-256 fingerprint of the certificate.
+func FingerprintSHA256(cert *x509.Certificate) string {
+	fp, _ := Fingerprint(cert.Raw, crypto.SHA256)
+	return fp
+}
+
+func colonHex(b []byte) string {
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02X", v)
+	}
+	return strings.Join(parts, ":")
+}
+
+// SPKIPin returns the base64 encoded SHA-256 pin of the certificate public key.
+func SPKIPin(cert *x509.Certificate) (string, error) {
+	return key.SPKIPin(cert.PublicKey)
+}
@@ -0,0 +1,76 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+// created with: openssl req -x509 -newkey ec -pkeyopt ec_paramgen_curve:P-256 -subj "/C=SE/O=test/CN=www.foo.se"
+var opensslCertPem = `-----BEGIN CERTIFICATE-----
+MIIBuDCCAV2gAwIBAgIUGO4plB6nZpOa8Xt3l8MXpVTrjiYwCgYIKoZIzj0EAwIw
+MTELMAkGA1UEBhMCU0UxDTALBgNVBAoMBHRlc3QxEzARBgNVBAMMCnd3dy5mb28u
+c2UwHhcNMjYxMDE2MDc1NjQzWhcNMzYxMDEzMDc1NjQzWjAxMQswCQYDVQQGEwJT
+RTENMAsGA1UECgwEdGVzdDETMBEGA1UEAwwKd3d3LmZvby5zZTBZMBMGByqGSM49
+AgEGCCqGSM49AwEHA0IABLXhALT33ujdjSsA3c3d4I4viT8DFeBJ9DFyobxBiSsA
+rNo6dcrml6nDcxcTXej2zG62IvWj20SjiD7XET9nMyejUzBRMB0GA1UdDgQWBBSf
+6BNobJ9BG/xalZkYa6W2X0+X4jAfBgNVHSMEGDAWgBSf6BNobJ9BG/xalZkYa6W2
+X0+X4jAPBgNVHRMBAf8EBTADAQH/MAoGCCqGSM49BAMCA0kAMEYCIQCVmq+Wep4+
+EZhqqMWaeQlX+Vhm/y8edsl0MreAj6iwTAIhAKgxymA9sQR0DaIQ5tfb9+58kqJt
+U2a5yqn6Crb7MbsE
+-----END CERTIFICATE-----
+`
+
+// openssl x509 -noout -fingerprint -sha256 / -sha1
+const (
+	opensslSHA256Fingerprint = "00:A0:04:95:CC:DD:1A:86:8D:CF:67:94:32:CA:BC:F9:CC:EB:BC:3B:9A:72:26:4B:1B:67:D5:96:3B:FC:42:D9"
+	opensslSHA1Fingerprint   = "B6:ED:9E:11:5B:08:90:5A:E6:BD:69:F8:88:85:BC:D1:A9:04:93:AB"
+)
+
+func TestFingerprintPem(t *testing.T) {
+	fp, err := Fingerprint([]byte(opensslCertPem), crypto.SHA256)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fp != opensslSHA256Fingerprint {
+		t.Fatalf("got: %s, want %s", fp, opensslSHA256Fingerprint)
+	}
+}
+
+func TestFingerprintDer(t *testing.T) {
+	block, _ := pem.Decode([]byte(opensslCertPem))
+	fp, err := Fingerprint(block.Bytes, crypto.SHA1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fp != opensslSHA1Fingerprint {
+		t.Fatalf("got: %s, want %s", fp, opensslSHA1Fingerprint)
+	}
+	cert, _ := x509.ParseCertificate(block.Bytes)
+	if fp := FingerprintSHA256(cert); fp != opensslSHA256Fingerprint {
+		t.Fatalf("got: %s, want %s", fp, opensslSHA256Fingerprint)
+	}
+}
+
+func TestFingerprintInvalidInput(t *testing.T) {
+	if _, err := Fingerprint([]byte(opensslCertPem), crypto.MD4); err == nil {
+		t.Fatal("expected error for unavailable hash")
+	}
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte{1}})
+	if _, err := Fingerprint(keyPem, crypto.SHA256); err == nil {
+		t.Fatal("expected error for non certificate PEM block")
+	}
+}
+
+func TestSPKIPin(t *testing.T) {
+	cert, _ := parseCertificatePem([]byte(opensslCertPem))
+	pin, err := SPKIPin(cert)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// openssl x509 -pubkey | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+	if pin != "F/aKzCIDErwl62yRN5RjThe915UAsIYCE1ICg1QW+GI=" {
+		t.Fatalf("got: %v, want F/aKzCIDErwl62yRN5RjThe915UAsIYCE1ICg1QW+GI=", pin)
+	}
+}
@@ -0,0 +1,48 @@
+package certificate
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// toASCIIName converts an internationalized domain name, such as bücher.example, to
+// the A-label form xn--bcher-kva.example used in certificates. ASCII names are
+// returned unchanged and a wildcard label is kept.
+func toASCIIName(name string) (string, error) {
+	if isASCII(name) {
+		return name, nil
+	}
+	wildcard := strings.HasPrefix(name, "*.")
+	ascii, err := idna.Lookup.ToASCII(strings.TrimPrefix(name, "*."))
+	if err != nil {
+		return "", fmt.Errorf("invalid internationalized domain name %q: %v", name, err)
+	}
+	if wildcard {
+		ascii = "*." + ascii
+	}
+	return ascii, nil
+}
+
+func toASCIINames(names []string) ([]string, error) {
+	var converted []string
+	for _, name := range names {
+		ascii, err := toASCIIName(name)
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, ascii)
+	}
+	return converted, nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
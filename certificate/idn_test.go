@@ -0,0 +1,51 @@
+package certificate
+
+import (
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestToASCIIName(t *testing.T) {
+	tests := map[string]string{
+		"bücher.example":   "xn--bcher-kva.example",
+		"*.bücher.example": "*.xn--bcher-kva.example",
+		"www.Foo.se":       "www.Foo.se",
+	}
+	for name, want := range tests {
+		got, err := toASCIIName(name)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if got != want {
+			t.Fatalf("%s: got: %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestInternationalizedNames(t *testing.T) {
+	caPriv := key.GenerateKey("P256", 0)
+	ca := mustCreateTemplate(t, Certificate{Id: "one", CA: true, PrivateKey: caPriv})
+	caBytes := Sign(ca, ca, key.PublicKey(caPriv), caPriv)
+	for _, keepUnicode := range []bool{false, true} {
+		priv := key.GenerateKey("P256", 0)
+		template := mustCreateTemplate(t, Certificate{Id: "two", CommonName: "bücher.example", AlternativeNames: []string{"www.bücher.example"}, PrivateKey: priv, KeepUnicodeCN: keepUnicode})
+		want := []string{"www.xn--bcher-kva.example", "xn--bcher-kva.example"}
+		if len(template.DNSNames) != 2 || template.DNSNames[0] != want[0] || template.DNSNames[1] != want[1] {
+			t.Fatalf("got: %v, want %v", template.DNSNames, want)
+		}
+		wantCN := "xn--bcher-kva.example"
+		if keepUnicode {
+			wantCN = "bücher.example"
+		}
+		if template.Subject.CommonName != wantCN {
+			t.Fatalf("got CN: %s, want %s", template.Subject.CommonName, wantCN)
+		}
+		clientBytes := Sign(template, ca, key.PublicKey(priv), caPriv)
+		for _, name := range []string{"bücher.example", "xn--bcher-kva.example", "www.bücher.example"} {
+			if !CheckCertificate(name, caBytes, nil, clientBytes) {
+				t.Fatalf("failed to verify %s", name)
+			}
+		}
+	}
+}
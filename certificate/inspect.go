@@ -0,0 +1,40 @@
+package certificate
+
+import "crypto/x509"
+
+// AllSANs returns all subject alternative names of the certificate labeled
+// by type the same way as openssl displays them, e.g. DNS:www.foo.se, IP:127.0.0.1,
+// email:foo@foo.se and URI:https://www.foo.se.
+func AllSANs(cert *x509.Certificate) []string {
+	var sans []string
+	for _, name := range cert.DNSNames {
+		sans = append(sans, "DNS:"+name)
+	}
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, "IP:"+ip.String())
+	}
+	for _, email := range cert.EmailAddresses {
+		sans = append(sans, "email:"+email)
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, "URI:"+uri.String())
+	}
+	return sans
+}
+
+// ExtensionInfo is an extension of a certificate, Value is the DER encoded content.
+type ExtensionInfo struct {
+	OID      string
+	Critical bool
+	Value    []byte
+}
+
+// ListExtensions returns every extension of the certificate in the order they are
+// encoded, the OIDs in dotted form such as 2.5.29.17.
+func ListExtensions(cert *x509.Certificate) []ExtensionInfo {
+	exts := make([]ExtensionInfo, 0, len(cert.Extensions))
+	for _, ext := range cert.Extensions {
+		exts = append(exts, ExtensionInfo{OID: ext.Id.String(), Critical: ext.Critical, Value: ext.Value})
+	}
+	return exts
+}
@@ -0,0 +1,48 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestAllSANs(t *testing.T) {
+	ca, caPriv := createCA()
+	uri, _ := url.Parse("https://www.foo.se/id")
+	ca.DNSNames = []string{"www.foo.se"}
+	ca.IPAddresses = []net.IP{net.ParseIP("1.2.3.4")}
+	ca.EmailAddresses = []string{"foo@foo.se"}
+	ca.URIs = []*url.URL{uri}
+	caBytes := Sign(ca, ca, key.PublicKey(caPriv), caPriv)
+	cert, _ := x509.ParseCertificate(caBytes)
+
+	want := []string{"DNS:www.foo.se", "IP:1.2.3.4", "email:foo@foo.se", "URI:https://www.foo.se/id"}
+	if got := AllSANs(cert); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v, want %v", got, want)
+	}
+}
+
+func TestListExtensions(t *testing.T) {
+	ca, caPriv := createCA()
+	client, clientPriv := createClient()
+	cert, _ := x509.ParseCertificate(Sign(client, ca, key.PublicKey(clientPriv), caPriv))
+	exts := ListExtensions(cert)
+	if len(exts) != len(cert.Extensions) {
+		t.Fatalf("got %d extensions, want %d", len(exts), len(cert.Extensions))
+	}
+	found := map[string]ExtensionInfo{}
+	for _, ext := range exts {
+		found[ext.OID] = ext
+	}
+	keyUsage, ok := found["2.5.29.15"]
+	if !ok || !keyUsage.Critical || len(keyUsage.Value) == 0 {
+		t.Fatalf("got: %+v, want a critical key usage extension", keyUsage)
+	}
+	if san, ok := found["2.5.29.17"]; !ok || len(san.Value) == 0 {
+		t.Fatalf("got: %+v, want a subject alternative name extension", exts)
+	}
+}
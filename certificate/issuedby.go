@@ -0,0 +1,53 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	// ErrIssuerNameMismatch is returned when the issuer name of the child is not the
+	// subject name of the parent.
+	ErrIssuerNameMismatch = errors.New("issuer name does not match the subject of the parent")
+	// ErrKeyIdMismatch is returned when the authority key identifier of the child is not
+	// the subject key identifier of the parent.
+	ErrKeyIdMismatch = errors.New("authority key identifier does not match the subject key identifier of the parent")
+	// ErrSignatureInvalid is returned when the signature of the child was not made by the
+	// key of the parent.
+	ErrSignatureInvalid = errors.New("signature was not made by the parent")
+)
+
+// IssuedBy checks that child was signed directly by parent, without building a chain.
+// The issuer name of child must equal the subject of parent, the key identifiers must
+// match when both are present and the signature must verify with the key of parent.
+// The error wraps ErrIssuerNameMismatch, ErrKeyIdMismatch or ErrSignatureInvalid.
+func IssuedBy(child, parent *x509.Certificate) error {
+	if !bytes.Equal(child.RawIssuer, parent.RawSubject) {
+		return fmt.Errorf("%w: issuer %v, parent %v", ErrIssuerNameMismatch, child.Issuer, parent.Subject)
+	}
+	if len(child.AuthorityKeyId) > 0 && len(parent.SubjectKeyId) > 0 && !bytes.Equal(child.AuthorityKeyId, parent.SubjectKeyId) {
+		return fmt.Errorf("%w: %s, parent %s", ErrKeyIdMismatch, colonHex(child.AuthorityKeyId), colonHex(parent.SubjectKeyId))
+	}
+	if err := child.CheckSignatureFrom(parent); err != nil {
+		return fmt.Errorf("%w: %v", ErrSignatureInvalid, err)
+	}
+	return nil
+}
+
+// IssuedByFiles is IssuedBy for PEM encoded certificates.
+func IssuedByFiles(childFile, parentFile string) error {
+	var certs [2]*x509.Certificate
+	for i, name := range []string{childFile, parentFile} {
+		certPem, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if certs[i], err = parseCertificatePem(certPem); err != nil {
+			return fmt.Errorf("failed to parse %s: %v", name, err)
+		}
+	}
+	return IssuedBy(certs[0], certs[1])
+}
@@ -0,0 +1,47 @@
+package certificate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIssuedBy(t *testing.T) {
+	issuer := testIssuer(t)
+	leaf := ocspLeaf(t, issuer)
+	if err := IssuedBy(leaf, issuer.Cert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := IssuedBy(issuer.Cert, leaf); !errors.Is(err, ErrIssuerNameMismatch) {
+		t.Fatalf("got: %v, want ErrIssuerNameMismatch", err)
+	}
+	// a CA with the same name but another key
+	other := testIssuer(t)
+	if err := IssuedBy(leaf, other.Cert); !errors.Is(err, ErrKeyIdMismatch) {
+		t.Fatalf("got: %v, want ErrKeyIdMismatch", err)
+	}
+	forged := *other.Cert
+	forged.SubjectKeyId = issuer.Cert.SubjectKeyId
+	if err := IssuedBy(leaf, &forged); !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("got: %v, want ErrSignatureInvalid", err)
+	}
+}
+
+func TestIssuedByFiles(t *testing.T) {
+	dir := t.TempDir()
+	issuer := testIssuer(t)
+	leafFile := filepath.Join(dir, "leaf.pem")
+	caFile := filepath.Join(dir, "ca.pem")
+	os.WriteFile(leafFile, encodeCertificatePem(ocspLeaf(t, issuer).Raw), 0600)
+	os.WriteFile(caFile, encodeCertificatePem(issuer.Cert.Raw), 0600)
+	if err := IssuedByFiles(leafFile, caFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := IssuedByFiles(caFile, leafFile); !errors.Is(err, ErrIssuerNameMismatch) {
+		t.Fatalf("got: %v, want ErrIssuerNameMismatch", err)
+	}
+	if err := IssuedByFiles(filepath.Join(dir, "missing.pem"), caFile); err == nil {
+		t.Fatal("expected error for a missing file")
+	}
+}
@@ -0,0 +1,338 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+// SerialSource hands out serial numbers for issued certificates.
+type SerialSource interface {
+	Next() (*big.Int, error)
+}
+
+// RandomSerials is a SerialSource returning random 128 bit serials.
+type RandomSerials struct{}
+
+func (RandomSerials) Next() (*big.Int, error) {
+	return randomSerial()
+}
+
+// MonotonicSerials is a SerialSource counting upwards from Start, or from 1 if Start is nil.
+type MonotonicSerials struct {
+	Start *big.Int
+	next  *big.Int
+}
+
+func (m *MonotonicSerials) Next() (*big.Int, error) {
+	if m.next == nil {
+		m.next = big.NewInt(1)
+		if m.Start != nil && m.Start.Sign() > 0 {
+			m.next.Set(m.Start)
+		}
+	}
+	serial := new(big.Int).Set(m.next)
+	m.next.Add(m.next, big.NewInt(1))
+	return serial, nil
+}
+
+// SignerValidity decides what happens to a certificate that would be valid after its
+// signer has expired.
+type SignerValidity int
+
+const (
+	// ClampToSigner cuts NotAfter to the NotAfter of the signer, the default.
+	ClampToSigner SignerValidity = iota
+	// RejectBeyondSigner fails the issuance.
+	RejectBeyondSigner
+	// AllowBeyondSigner issues the certificate as requested.
+	AllowBeyondSigner
+)
+
+// applySignerValidity applies policy to a template that outlives its signer.
+func applySignerValidity(template, signer *x509.Certificate, policy SignerValidity) error {
+	if !template.NotAfter.After(signer.NotAfter) {
+		return nil
+	}
+	switch policy {
+	case RejectBeyondSigner:
+		return fmt.Errorf("certificate valid %v to %v outlives its signer valid %v to %v", template.NotBefore, template.NotAfter, signer.NotBefore, signer.NotAfter)
+	case AllowBeyondSigner:
+		return nil
+	default:
+		template.NotAfter = signer.NotAfter
+		if !template.NotBefore.Before(template.NotAfter) {
+			return fmt.Errorf("certificate valid from %v starts after its signer valid %v to %v has expired", template.NotBefore, signer.NotBefore, signer.NotAfter)
+		}
+		return nil
+	}
+}
+
+// Issuer signs certificates with a CA certificate and key. It is safe for concurrent
+// use, serials are allocated under a lock and are never handed out twice.
+type Issuer struct {
+	Cert    *x509.Certificate
+	Key     interface{}
+	Serials SerialSource
+	// Store, if set, gets every issued certificate. A failure to store fails the issuance.
+	Store IssuanceStore
+	// Strict makes Issue refuse certificates with LintTemplate violations, except for
+	// the codes in AllowedViolations.
+	Strict            bool
+	AllowedViolations []string
+	// MaxLeafValidity overrides DefaultMaxLeafValidity for server certificates in strict mode.
+	MaxLeafValidity time.Duration
+	// BeyondSigner decides what to do with certificates that would outlive the issuer
+	// certificate, by default their NotAfter is clamped.
+	BeyondSigner SignerValidity
+	// PreSignHook, if set, is called with every certificate after the built-in checks
+	// and before it is signed. An error aborts the issuance, which allows central
+	// enforcement of policies like the CA/Browser Forum requirements.
+	PreSignHook func(*x509.Certificate) error
+	// SerialExists, if set, is asked if a serial is already used, e.g. by an earlier
+	// issuer for the same CA. Used serials are skipped when allocating a serial and
+	// make IssueWithSerial fail.
+	SerialExists func(*big.Int) bool
+	// MinRSAKeyBits, if set, is the shortest RSA key the issuer issues certificates for,
+	// e.g. 3072 for a stricter policy than key.MinRSAKeyBits. A certificate with
+	// AllowWeakKey is not held to it.
+	MinRSAKeyBits int
+
+	mu     sync.Mutex
+	issued map[string]bool
+}
+
+// NewIssuer creates an Issuer with random serials for the given CA certificate and key.
+func NewIssuer(cert *x509.Certificate, key interface{}) *Issuer {
+	return &Issuer{Cert: cert, Key: key, Serials: RandomSerials{}}
+}
+
+// Issue signs a certificate for data, the serial number is assigned by the issuer. If
+// data has no private key one of its KeyType is generated, call EnsureKey first to
+// keep the generated key.
+func (i *Issuer) Issue(data Certificate) ([]byte, error) {
+	serial, err := i.nextSerial()
+	if err != nil {
+		return nil, err
+	}
+	return i.issue(data, serial)
+}
+
+// IssueWithSerial signs a certificate for data with an explicit serial number. It
+// fails if the serial has already been used.
+func (i *Issuer) IssueWithSerial(data Certificate, serial *big.Int) ([]byte, error) {
+	if err := i.reserveSerial(serial); err != nil {
+		return nil, err
+	}
+	return i.issue(data, serial)
+}
+
+// PreviewTemplate returns the template Issue would sign for data, after all checks of
+// the issuer, without signing or storing it. No serial is allocated or reserved, the
+// serial of the preview is data.SerialNumber or the one derived from data.Id as with
+// NewCertificateTemplate. As Issue does not use the Id, an Id that gives no valid
+// serial gets a random one.
+func (i *Issuer) PreviewTemplate(data Certificate) (*x509.Certificate, error) {
+	serial := data.SerialNumber
+	if serial == nil {
+		var err error
+		if serial, err = serialNumber(data.Id); err != nil {
+			if serial, err = randomSerial(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return i.prepare(&data, serial)
+}
+
+func (i *Issuer) issue(data Certificate, serial *big.Int) ([]byte, error) {
+	template, err := i.prepare(&data, serial)
+	if err != nil {
+		return nil, err
+	}
+	return i.signAndStore(template, data, serial)
+}
+
+// prepare creates the template for data and runs the checks of the issuer on it. A
+// key of the declared KeyType is generated into data if it has none.
+func (i *Issuer) prepare(data *Certificate, serial *big.Int) (*x509.Certificate, error) {
+	template, err := issueTemplate(data, serial, i.Key)
+	if err != nil {
+		return nil, err
+	}
+	if err := applySignerValidity(template, i.Cert, i.BeyondSigner); err != nil {
+		return nil, err
+	}
+	if i.MinRSAKeyBits != 0 && !data.AllowWeakKey {
+		if err := checkRSAKeyBits(key.PublicKey(data.PrivateKey), i.MinRSAKeyBits); err != nil {
+			return nil, err
+		}
+	}
+	if i.Strict {
+		maxLeafValidity := i.MaxLeafValidity
+		if maxLeafValidity == 0 {
+			maxLeafValidity = DefaultMaxLeafValidity
+		}
+		violations := lintTemplate(template, key.PublicKey(data.PrivateKey), maxLeafValidity, time.Now())
+		violations = filterViolations(violations, i.AllowedViolations)
+		if len(violations) > 0 {
+			return nil, &LintError{Violations: violations}
+		}
+	}
+	if i.PreSignHook != nil {
+		if err := i.PreSignHook(template); err != nil {
+			return nil, fmt.Errorf("certificate %v rejected before signing: %w", template.Subject, err)
+		}
+	}
+	return template, nil
+}
+
+func (i *Issuer) signAndStore(template *x509.Certificate, data Certificate, serial *big.Int) ([]byte, error) {
+	der, err := signIssued(template, data, i.Cert, i.Key)
+	if err != nil {
+		return nil, err
+	}
+	if i.Store != nil {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		meta := IssueMeta{
+			CommonName: cert.Subject.CommonName,
+			IssuedAt:   time.Now(),
+			NotBefore:  cert.NotBefore,
+			NotAfter:   cert.NotAfter,
+		}
+		if err := i.Store.Put(serial, der, meta); err != nil {
+			return nil, fmt.Errorf("failed to store issued certificate %v: %v", serial, err)
+		}
+	}
+	return der, nil
+}
+
+// issueCertificate signs data with the given serial, the signature algorithm is
+// chosen to match the key of the signer.
+func issueCertificate(data Certificate, serial *big.Int, signer *x509.Certificate, signerKey interface{}) ([]byte, error) {
+	template, err := issueTemplate(&data, serial, signerKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := applySignerValidity(template, signer, ClampToSigner); err != nil {
+		return nil, err
+	}
+	return signIssued(template, data, signer, signerKey)
+}
+
+// PreviewTemplate returns the template a certificate would be issued from, after the
+// checks shared by all issue paths, without signing it. The serial is data.SerialNumber
+// or taken from data.Id as with NewCertificateTemplate. As on issuance a key of the declared KeyType
+// is generated if data has none. The validity is not yet cut to a signer and the
+// checks of an Issuer are not run, use (*Issuer).PreviewTemplate for those.
+func PreviewTemplate(data Certificate) (*x509.Certificate, error) {
+	if err := data.EnsureKey(); err != nil {
+		return nil, err
+	}
+	template, err := NewCertificateTemplate(data)
+	if err != nil {
+		return nil, err
+	}
+	if isServerCertificate(template) && len(template.DNSNames)+len(template.IPAddresses) == 0 {
+		// browsers no longer fall back to the common name
+		return nil, fmt.Errorf("server certificate %v has no DNS or IP subject alternative names", template.Subject)
+	}
+	return template, nil
+}
+
+// issueTemplate creates the template for data with the serial, generating a key of
+// the declared KeyType into data if it has none.
+func issueTemplate(data *Certificate, serial *big.Int, signerKey interface{}) (*x509.Certificate, error) {
+	if err := data.EnsureKey(); err != nil {
+		return nil, err
+	}
+	// the serial is given, a serial derived from the Id is neither used nor checked
+	data.SerialNumber = serial
+	template, err := PreviewTemplate(*data)
+	if err != nil {
+		return nil, err
+	}
+	template.SignatureAlgorithm = signatureAlgorithm(data.SignatureAlg, signerKey)
+	return template, nil
+}
+
+func signIssued(template *x509.Certificate, data Certificate, signer *x509.Certificate, signerKey interface{}) ([]byte, error) {
+	der, err := SignCertificate(template, signer, key.PublicKey(data.PrivateKey), signerKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue certificate: %v", err)
+	}
+	return der, nil
+}
+
+// maxSerialAttempts is how many used serials nextSerial skips before it gives up, so
+// a Serials source that only returns used serials fails instead of hanging.
+const maxSerialAttempts = 1000
+
+func (i *Issuer) nextSerial() (*big.Int, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.Serials == nil {
+		i.Serials = RandomSerials{}
+	}
+	for attempt := 0; attempt < maxSerialAttempts; attempt++ {
+		serial, err := i.Serials.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to allocate serial: %v", err)
+		}
+		used, err := i.serialUsed(serial)
+		if err != nil {
+			return nil, err
+		}
+		if !used {
+			i.issued[serial.String()] = true
+			return serial, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to allocate serial: %d serials in a row are already used", maxSerialAttempts)
+}
+
+func (i *Issuer) reserveSerial(serial *big.Int) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	used, err := i.serialUsed(serial)
+	if err != nil {
+		return err
+	}
+	if used {
+		return fmt.Errorf("serial %v is already used", serial)
+	}
+	i.issued[serial.String()] = true
+	return nil
+}
+
+// serialUsed must be called with the lock held.
+func (i *Issuer) serialUsed(serial *big.Int) (bool, error) {
+	if i.issued == nil {
+		i.issued = map[string]bool{}
+	}
+	if i.issued[serial.String()] {
+		return true, nil
+	}
+	if i.SerialExists != nil && i.SerialExists(serial) {
+		return true, nil
+	}
+	if i.Store != nil {
+		_, err := i.Store.Get(serial)
+		if err == nil {
+			// issued before a restart
+			return true, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return false, fmt.Errorf("failed to look up serial %v: %v", serial, err)
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,399 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/big"
+	mathrand "math/rand/v2"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func testIssuer(t *testing.T) *Issuer {
+	ca, err := NewCA(Certificate{Id: "ca", OrganizationalUnit: "WebCA", CA: true, PrivateKey: key.GenerateKey("P256", 0)})
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	return NewIssuer(ca.Cert, ca.Key)
+}
+
+func issueConcurrently(t *testing.T, issuer *Issuer) map[string]bool {
+	leafKey := key.GenerateKey("P256", 0)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	serials := map[string]bool{}
+	for g := 0; g < 10; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 10; n++ {
+				der, err := issuer.Issue(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: leafKey, AllowWeakKey: true})
+				if err != nil {
+					t.Errorf("failed to issue: %v", err)
+					return
+				}
+				cert, _ := x509.ParseCertificate(der)
+				mu.Lock()
+				if serials[cert.SerialNumber.String()] {
+					t.Errorf("serial %v issued twice", cert.SerialNumber)
+				}
+				serials[cert.SerialNumber.String()] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return serials
+}
+
+func TestIssuerConcurrentRandom(t *testing.T) {
+	if serials := issueConcurrently(t, testIssuer(t)); len(serials) != 100 {
+		t.Fatalf("got %d unique serials, want 100", len(serials))
+	}
+}
+
+func TestIssuerConcurrentMonotonic(t *testing.T) {
+	issuer := testIssuer(t)
+	issuer.Serials = &MonotonicSerials{}
+	serials := issueConcurrently(t, issuer)
+	if len(serials) != 100 {
+		t.Fatalf("got %d unique serials, want 100", len(serials))
+	}
+	for _, s := range []string{"1", "50", "100"} {
+		if !serials[s] {
+			t.Fatalf("serial %s missing", s)
+		}
+	}
+}
+
+func TestIssueLongNonNumericId(t *testing.T) {
+	// the id gives a serial longer than 20 octets, the issuers use their own serial
+	data := Certificate{Id: "frontend-server-production-01", CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: key.GenerateKey("P256", 0)}
+	issuer := testIssuer(t)
+	der, err := issuer.Issue(data)
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	if serial := parseCert(t, der).SerialNumber; serial.BitLen() > 159 {
+		t.Fatalf("got serial %v, want at most 20 octets", serial)
+	}
+	if _, err := issuer.PreviewTemplate(data); err != nil {
+		t.Fatalf("failed to preview: %v", err)
+	}
+	ca, err := NewCA(Certificate{Id: "ca", CommonName: "ca", CA: true, PrivateKey: key.GenerateKey("P256", 0)})
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	if _, err := ca.Issue(data); err != nil {
+		t.Fatalf("failed to issue with the CA: %v", err)
+	}
+}
+
+func TestIssuerDeterministicWithRandReader(t *testing.T) {
+	defer func(r io.Reader) { RandReader = r }(RandReader)
+	caKey := key.GenerateKey("RSA", 1024)
+	leafKey := key.GenerateKey("RSA", 1024)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	issue := func() []byte {
+		RandReader = mathrand.NewChaCha8([32]byte{1})
+		ca, err := NewCA(Certificate{Id: "ca", CommonName: "ca", CA: true, PrivateKey: caKey, AllowWeakKey: true, ValidFrom: from, ValidTo: from.AddDate(1, 0, 0)})
+		if err != nil {
+			t.Fatalf("failed to create CA: %v", err)
+		}
+		der, err := NewIssuer(ca.Cert, ca.Key).Issue(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: leafKey, AllowWeakKey: true, ValidFrom: from, ValidTo: from.AddDate(0, 1, 0)})
+		if err != nil {
+			t.Fatalf("failed to issue: %v", err)
+		}
+		return der
+	}
+	if !bytes.Equal(issue(), issue()) {
+		t.Fatal("certificates issued with the same seed differ")
+	}
+}
+
+func TestIssuerSerialExistsRetries(t *testing.T) {
+	defer func(r io.Reader) { RandReader = r }(RandReader)
+	issuer := testIssuer(t)
+	RandReader = mathrand.NewChaCha8([32]byte{2})
+	taken, _ := randomSerial()
+	calls := 0
+	issuer.SerialExists = func(serial *big.Int) bool {
+		calls++
+		return serial.Cmp(taken) == 0
+	}
+	// the same seed makes the first draw collide with the taken serial
+	RandReader = mathrand.NewChaCha8([32]byte{2})
+	der, err := issuer.Issue(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: key.GenerateKey("P256", 0)})
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	if cert.SerialNumber.Cmp(taken) == 0 || calls != 2 {
+		t.Fatalf("got serial %v after %d draws, want a new serial after 2", cert.SerialNumber, calls)
+	}
+}
+
+func TestIssuerSerialExistsGivesUp(t *testing.T) {
+	issuer := testIssuer(t)
+	calls := 0
+	issuer.SerialExists = func(serial *big.Int) bool {
+		calls++
+		return true
+	}
+	if _, err := issuer.Issue(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: key.GenerateKey("P256", 0)}); err == nil {
+		t.Fatal("expected error when every serial is used")
+	}
+	if calls != maxSerialAttempts {
+		t.Fatalf("got %d draws, want %d", calls, maxSerialAttempts)
+	}
+}
+
+func TestIssuerIssueWithSerial(t *testing.T) {
+	issuer := testIssuer(t)
+	issuer.SerialExists = func(serial *big.Int) bool { return serial.Int64() == 7 }
+	leaf := Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: key.GenerateKey("P256", 0)}
+	if _, err := issuer.IssueWithSerial(leaf, big.NewInt(7)); err == nil {
+		t.Fatal("expected error for an existing serial")
+	}
+	der, err := issuer.IssueWithSerial(leaf, big.NewInt(8))
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	if cert, _ := x509.ParseCertificate(der); cert.SerialNumber.Int64() != 8 {
+		t.Fatalf("got serial %v, want 8", cert.SerialNumber)
+	}
+	if _, err := issuer.IssueWithSerial(leaf, big.NewInt(8)); err == nil {
+		t.Fatal("expected error for a serial issued twice")
+	}
+}
+
+func TestIssuerBeyondSigner(t *testing.T) {
+	from := time.Now().Truncate(time.Second)
+	ca, err := NewCA(Certificate{Id: "ca", CommonName: "ca", CA: true, PrivateKey: key.GenerateKey("P256", 0), ValidFrom: from, ValidTo: from.AddDate(1, 0, 0)})
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	leaf := Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: key.GenerateKey("P256", 0), ValidFrom: from, ValidTo: from.AddDate(2, 0, 0)}
+
+	issuer := NewIssuer(ca.Cert, ca.Key)
+	der, err := issuer.Issue(leaf)
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	if !cert.NotAfter.Equal(ca.Cert.NotAfter) {
+		t.Fatalf("NotAfter %v, want clamped to %v", cert.NotAfter, ca.Cert.NotAfter)
+	}
+
+	issuer.BeyondSigner = RejectBeyondSigner
+	if _, err := issuer.Issue(leaf); err == nil || !strings.Contains(err.Error(), ca.Cert.NotAfter.String()) {
+		t.Fatalf("got %v, want error showing the signer validity", err)
+	}
+
+	issuer.BeyondSigner = AllowBeyondSigner
+	der, err = issuer.Issue(leaf)
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	cert, _ = x509.ParseCertificate(der)
+	if !cert.NotAfter.Equal(leaf.ValidTo) {
+		t.Fatalf("NotAfter %v, want %v", cert.NotAfter, leaf.ValidTo)
+	}
+
+	issuer.BeyondSigner = ClampToSigner
+	late := leaf
+	late.ValidFrom = ca.Cert.NotAfter.Add(time.Hour)
+	if _, err := issuer.Issue(late); err == nil {
+		t.Fatal("issued a certificate starting after the signer expired")
+	}
+}
+
+func TestIssuerEmptyValidity(t *testing.T) {
+	from := time.Now()
+	_, err := testIssuer(t).Issue(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: key.GenerateKey("P256", 0), ValidFrom: from, ValidTo: from.Add(-time.Hour)})
+	if err == nil || !strings.Contains(err.Error(), "empty validity period") {
+		t.Fatalf("got %v, want empty validity error", err)
+	}
+}
+
+func TestIssuerPreSignHook(t *testing.T) {
+	errTooLong := errors.New("TLS server certificate valid longer than 398 days")
+	issuer := testIssuer(t)
+	issuer.BeyondSigner = AllowBeyondSigner
+	issuer.PreSignHook = func(cert *x509.Certificate) error {
+		if isServerCertificate(cert) && cert.NotAfter.Sub(cert.NotBefore) > 398*24*time.Hour {
+			return errTooLong
+		}
+		return nil
+	}
+	from := time.Now()
+	leaf := Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, Usage: []string{"serverauth"}, PrivateKey: key.GenerateKey("P256", 0), ValidFrom: from, ValidTo: from.AddDate(2, 0, 0)}
+	if _, err := issuer.Issue(leaf); !errors.Is(err, errTooLong) {
+		t.Fatalf("got: %v, want the hook error", err)
+	}
+	leaf.ValidTo = from.AddDate(0, 0, 90)
+	if _, err := issuer.Issue(leaf); err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+}
+
+func TestIssuerRejectsServerCertificateWithoutSAN(t *testing.T) {
+	issuer := testIssuer(t)
+	cnOnly := Certificate{CommonName: "www.foo.se", Usage: []string{"serverauth"}, PrivateKey: key.GenerateKey("P256", 0)}
+	if _, err := issuer.Issue(cnOnly); err == nil || !strings.Contains(err.Error(), "subject alternative names") {
+		t.Fatalf("got: %v, want error for a server certificate without SANs", err)
+	}
+	client := Certificate{CommonName: "client", Usage: []string{"clientauth"}, PrivateKey: key.GenerateKey("P256", 0)}
+	if _, err := issuer.Issue(client); err != nil {
+		t.Fatalf("failed to issue client certificate without SANs: %v", err)
+	}
+	cnOnly.IPAddresses = []net.IP{net.ParseIP("10.0.0.1")}
+	if _, err := issuer.Issue(cnOnly); err != nil {
+		t.Fatalf("failed to issue server certificate with an IP SAN: %v", err)
+	}
+}
+
+func TestConcurrentIssuers(t *testing.T) {
+	ca, err := NewCA(Certificate{Id: "ca", OrganizationalUnit: "WebCA", CA: true, PrivateKey: key.GenerateKey("P256", 0)})
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	leafKey := key.GenerateKey("P256", 0)
+	var wg sync.WaitGroup
+	for g := 0; g < 100; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			issuer := NewIssuer(ca.Cert, ca.Key)
+			if _, err := issuer.Issue(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: leafKey, AllowWeakKey: true}); err != nil {
+				t.Errorf("failed to issue: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// BenchmarkConcurrentIssue issues from one Issuer in parallel, run it with -race to
+// check that issuance shares no unguarded state.
+func BenchmarkConcurrentIssue(b *testing.B) {
+	ca, err := NewCA(Certificate{Id: "ca", OrganizationalUnit: "WebCA", CA: true, PrivateKey: key.GenerateKey("P256", 0)})
+	if err != nil {
+		b.Fatalf("failed to create CA: %v", err)
+	}
+	issuer := NewIssuer(ca.Cert, ca.Key)
+	leafKey := key.GenerateKey("P256", 0)
+	b.SetParallelism(100)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := issuer.Issue(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: leafKey, AllowWeakKey: true}); err != nil {
+				b.Errorf("failed to issue: %v", err)
+			}
+		}
+	})
+}
+
+func TestIssuerRejectsWeakRSAKey(t *testing.T) {
+	issuer := testIssuer(t)
+	leafKey := key.GenerateKey("RSA", 1024)
+	data := Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: leafKey}
+	if _, err := issuer.Issue(data); err == nil {
+		t.Fatal("expected a 1024 bit RSA key to be rejected")
+	}
+	if _, err := NewCertificateTemplate(data); err == nil {
+		t.Fatal("expected NewCertificateTemplate to reject a 1024 bit RSA key")
+	}
+	if _, err := NewCA(Certificate{CommonName: "ca", CA: true, PrivateKey: leafKey}); err == nil {
+		t.Fatal("expected NewCA to reject a 1024 bit RSA key")
+	}
+	data.AllowWeakKey = true
+	template, err := NewCertificateTemplate(data)
+	if err != nil {
+		t.Fatalf("unexpected error with AllowWeakKey: %v", err)
+	}
+	if violations := LintTemplate(template, key.PublicKey(leafKey)); len(violations) == 0 || violations[0].Code != LintWeakRSAKey {
+		t.Fatalf("got %v, want a weak RSA key violation", violations)
+	}
+	if _, err := issuer.Issue(data); err != nil {
+		t.Fatalf("unexpected error with AllowWeakKey: %v", err)
+	}
+}
+
+func TestIssuerMinRSAKeyBits(t *testing.T) {
+	issuer := testIssuer(t)
+	issuer.MinRSAKeyBits = 3072
+	data := Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: key.GenerateKey("RSA", 2048)}
+	if _, err := issuer.Issue(data); err == nil {
+		t.Fatal("expected a 2048 bit RSA key to be rejected by a 3072 bit issuer")
+	}
+	data.PrivateKey = key.GenerateKey("RSA", 3072)
+	if _, err := issuer.Issue(data); err != nil {
+		t.Fatalf("unexpected error for a 3072 bit key: %v", err)
+	}
+}
+
+func TestPreviewTemplateMatchesSignedCertificate(t *testing.T) {
+	from := time.Now().Truncate(time.Second)
+	data := Certificate{
+		Id:               "1234",
+		CommonName:       "www.foo.se",
+		Organization:     "Foo AB",
+		AlternativeNames: []string{"www.foo.se", "foo.se"},
+		IPAddresses:      []net.IP{net.ParseIP("10.0.0.1")},
+		PrivateKey:       key.GenerateKey("P256", 0),
+		ValidFrom:        from,
+		// beyond the CA, the issuer cuts it to the CA
+		ValidTo: from.AddDate(5, 0, 0),
+	}
+	issuer := testIssuer(t)
+	hooked := 0
+	issuer.PreSignHook = func(*x509.Certificate) error {
+		hooked++
+		return nil
+	}
+	preview, err := issuer.PreviewTemplate(data)
+	if err != nil {
+		t.Fatalf("failed to preview: %v", err)
+	}
+	if hooked != 1 {
+		t.Fatalf("pre-sign hook ran %d times on preview, want 1", hooked)
+	}
+	der, err := issuer.Issue(data)
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	cert := parseCert(t, der)
+	if preview.Subject.String() != cert.Subject.String() ||
+		!reflect.DeepEqual(preview.DNSNames, cert.DNSNames) ||
+		!preview.IPAddresses[0].Equal(cert.IPAddresses[0]) ||
+		preview.KeyUsage != cert.KeyUsage ||
+		!reflect.DeepEqual(preview.ExtKeyUsage, cert.ExtKeyUsage) ||
+		!bytes.Equal(preview.SubjectKeyId, cert.SubjectKeyId) ||
+		preview.SignatureAlgorithm != cert.SignatureAlgorithm ||
+		!preview.NotBefore.Equal(cert.NotBefore) || !preview.NotAfter.Equal(cert.NotAfter) {
+		t.Fatalf("preview %+v differs from the issued certificate %+v", preview, cert)
+	}
+	if !cert.NotAfter.Equal(issuer.Cert.NotAfter) {
+		t.Fatalf("got NotAfter %v, want it cut to the CA %v", cert.NotAfter, issuer.Cert.NotAfter)
+	}
+	// the preview did not reserve its serial
+	if preview.SerialNumber.Cmp(big.NewInt(1234)) != 0 {
+		t.Fatalf("got serial %v, want 1234 from the id", preview.SerialNumber)
+	}
+	if _, err := issuer.IssueWithSerial(data, preview.SerialNumber); err != nil {
+		t.Fatalf("failed to issue with the previewed serial: %v", err)
+	}
+	if _, err := issuer.PreviewTemplate(Certificate{CommonName: "www.foo.se", PrivateKey: key.GenerateKey("P256", 0)}); err == nil {
+		t.Fatal("expected a server certificate without SANs to fail the preview")
+	}
+	if _, err := PreviewTemplate(Certificate{CommonName: "www.foo.se", PrivateKey: key.GenerateKey("P256", 0)}); err == nil {
+		t.Fatal("expected a server certificate without SANs to fail the preview")
+	}
+}
@@ -0,0 +1,46 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+)
+
+// WriteJKS writes the leaf certificate, its private key and the rest of the chain to a
+// Java keystore file as a private key entry with the given alias. The same password
+// protects the key and the integrity of the keystore, as keytool does by default.
+// Java stores aliases in lower case.
+func WriteJKS(path, password string, alias string, leafDER []byte, key interface{}, chain [][]byte) error {
+	data, err := encodeJKS(password, alias, leafDER, key, chain, time.Now())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func encodeJKS(password, alias string, leafDER []byte, key interface{}, chain [][]byte, created time.Time) ([]byte, error) {
+	if _, err := x509.ParseCertificate(leafDER); err != nil {
+		return nil, fmt.Errorf("failed to parse leaf certificate: %v", err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %v", err)
+	}
+	entry := keystore.PrivateKeyEntry{CreationTime: created, PrivateKey: pkcs8}
+	for _, der := range append([][]byte{leafDER}, chain...) {
+		entry.CertificateChain = append(entry.CertificateChain, keystore.Certificate{Type: "X.509", Content: der})
+	}
+	ks := keystore.New(keystore.WithCustomRandomNumberGenerator(RandReader))
+	if err := ks.SetPrivateKeyEntry(alias, entry, []byte(password)); err != nil {
+		return nil, fmt.Errorf("failed to add key to keystore: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := ks.Store(&buf, []byte(password)); err != nil {
+		return nil, fmt.Errorf("failed to write keystore: %v", err)
+	}
+	return buf.Bytes(), nil
+}
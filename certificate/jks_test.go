@@ -0,0 +1,57 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+	"github.com/pavlo-v-chernykh/keystore-go/v4"
+)
+
+func TestWriteJKS(t *testing.T) {
+	issuer := testIssuer(t)
+	priv := key.GenerateKey("P256", 0)
+	leafDER, err := issuer.Issue(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: priv})
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "keystore.jks")
+	if err := WriteJKS(path, "changeit", "Server", leafDER, priv, [][]byte{issuer.Cert.Raw}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open keystore: %v", err)
+	}
+	defer f.Close()
+	ks := keystore.New()
+	if err := ks.Load(f, []byte("changeit")); err != nil {
+		t.Fatalf("failed to load keystore: %v", err)
+	}
+	if aliases := ks.Aliases(); len(aliases) != 1 || aliases[0] != "server" {
+		t.Fatalf("got aliases: %v, want server", aliases)
+	}
+	entry, err := ks.GetPrivateKeyEntry("server", []byte("changeit"))
+	if err != nil {
+		t.Fatalf("failed to read private key entry: %v", err)
+	}
+	got, err := x509.ParsePKCS8PrivateKey(entry.PrivateKey)
+	if err != nil || !reflect.DeepEqual(got, priv) {
+		t.Fatalf("private key differs: %v", err)
+	}
+	chain := entry.CertificateChain
+	if len(chain) != 2 || chain[0].Type != "X.509" || !bytes.Equal(chain[0].Content, leafDER) || !bytes.Equal(chain[1].Content, issuer.Cert.Raw) {
+		t.Fatal("certificate chain differs")
+	}
+	if _, err := ks.GetPrivateKeyEntry("server", []byte("wrong")); err == nil {
+		t.Fatal("expected error for a wrong key password")
+	}
+	f.Seek(0, 0)
+	if err := keystore.New().Load(f, []byte("wrong")); err == nil {
+		t.Fatal("expected error for a wrong keystore password")
+	}
+}
@@ -0,0 +1,76 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+var (
+	// ErrKeyTypeMismatch is returned when the private key is of another type than the certificate key.
+	ErrKeyTypeMismatch = errors.New("private key and certificate key are of different types")
+	// ErrKeyMismatch is returned when the keys are of the same type but the private key
+	// does not belong to the certificate.
+	ErrKeyMismatch = errors.New("private key does not match the certificate")
+)
+
+// KeyMatchesCertificate reports whether the private key belongs to the certificate.
+// RSA, ECDSA and Ed25519 keys are supported. A key of another type than the
+// certificate key does not match, an error is only returned for a value that is not
+// a private key. Use CheckKeyMatchesCertificate to tell the kinds of mismatch apart.
+func KeyMatchesCertificate(priv interface{}, cert *x509.Certificate) (bool, error) {
+	err := CheckKeyMatchesCertificate(cert, priv)
+	if errors.Is(err, ErrKeyTypeMismatch) || errors.Is(err, ErrKeyMismatch) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CheckKeyMatchesCertificate returns nil if the private key belongs to the certificate,
+// ErrKeyTypeMismatch if the keys are of different types and ErrKeyMismatch if they are
+// of the same type but the private key is another one.
+func CheckKeyMatchesCertificate(cert *x509.Certificate, priv interface{}) error {
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("unsupported private key type: %T", priv)
+	}
+	pub := signer.Public()
+	if reflect.TypeOf(pub) != reflect.TypeOf(cert.PublicKey) {
+		return ErrKeyTypeMismatch
+	}
+	if !publicKeysEqual(cert.PublicKey, pub) {
+		return ErrKeyMismatch
+	}
+	return nil
+}
+
+// KeyMatchesCertificateFiles reports whether the PEM encoded private key in keyFile
+// belongs to the certificate in certFile. A mismatch is returned as ErrKeyTypeMismatch
+// or ErrKeyMismatch as by CheckKeyMatchesCertificate.
+func KeyMatchesCertificateFiles(certFile, keyFile string) (bool, error) {
+	certPem, err := os.ReadFile(certFile)
+	if err != nil {
+		return false, err
+	}
+	cert, err := parseCertificatePem(certPem)
+	if err != nil {
+		return false, err
+	}
+	keyPem, err := os.ReadFile(keyFile)
+	if err != nil {
+		return false, err
+	}
+	priv, err := key.ParsePrivateKeyPem(keyPem)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	if err := CheckKeyMatchesCertificate(cert, priv); err != nil {
+		return false, err
+	}
+	return true, nil
+}
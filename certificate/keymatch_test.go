@@ -0,0 +1,78 @@
+package certificate
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func selfSigned(t *testing.T, priv interface{}) *x509.Certificate {
+	template := &x509.Certificate{SerialNumber: big.NewInt(1)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.PublicKey(priv), priv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+func TestKeyMatchesCertificate(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherEdKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey := key.GenerateKey("RSA", 1024)
+	ecKey := key.GenerateKey("P256", 0)
+	tests := []struct {
+		name string
+		priv interface{}
+		cert *x509.Certificate
+		err  error
+	}{
+		{"rsa", rsaKey, selfSigned(t, rsaKey), nil},
+		{"ecdsa", ecKey, selfSigned(t, ecKey), nil},
+		{"ed25519", edKey, selfSigned(t, edKey), nil},
+		{"other ed25519", otherEdKey, selfSigned(t, edKey), ErrKeyMismatch},
+		{"other ecdsa", key.GenerateKey("P256", 0), selfSigned(t, ecKey), ErrKeyMismatch},
+		{"mismatched types", ecKey, selfSigned(t, rsaKey), ErrKeyTypeMismatch},
+	}
+	for _, test := range tests {
+		if err := CheckKeyMatchesCertificate(test.cert, test.priv); !errors.Is(err, test.err) {
+			t.Fatalf("%s: got: %v, want %v", test.name, err, test.err)
+		}
+		// a mismatch is no error for KeyMatchesCertificate
+		match, err := KeyMatchesCertificate(test.priv, test.cert)
+		if err != nil || match != (test.err == nil) {
+			t.Fatalf("%s: got match %v with error %v", test.name, match, err)
+		}
+	}
+	if _, err := KeyMatchesCertificate("not a key", selfSigned(t, ecKey)); err == nil {
+		t.Fatal("expected error for a value that is not a private key")
+	}
+	if err := CheckKeyMatchesCertificate(selfSigned(t, ecKey), "not a key"); err == nil {
+		t.Fatal("expected error for a value that is not a private key")
+	}
+}
+
+func TestKeyMatchesCertificateFiles(t *testing.T) {
+	dir := t.TempDir()
+	priv := key.GenerateKey("P256", 0)
+	certFile := filepath.Join(dir, "crt.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	keyPem, _ := key.EncodePrivateKeyPem(priv)
+	os.WriteFile(certFile, encodeCertificatePem(selfSigned(t, priv).Raw), 0600)
+	os.WriteFile(keyFile, keyPem, 0600)
+	if match, err := KeyMatchesCertificateFiles(certFile, keyFile); !match || err != nil {
+		t.Fatalf("got: %v %v, want match", match, err)
+	}
+	otherPem, _ := key.EncodePrivateKeyPem(key.GenerateKey("P256", 0))
+	os.WriteFile(keyFile, otherPem, 0600)
+	if _, err := KeyMatchesCertificateFiles(certFile, keyFile); !errors.Is(err, ErrKeyMismatch) {
+		t.Fatalf("got: %v, want ErrKeyMismatch", err)
+	}
+}
@@ -0,0 +1,133 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+// defaultRSABits is the length of generated RSA keys if RSABits is not set.
+const defaultRSABits = 2048
+
+// keyTypes are the values accepted in Certificate.KeyType.
+var keyTypes = []string{"rsa", "rsa2048", "rsa4096", "ec256", "ec384", "ec521", "ed25519"}
+
+// EnsureKey generates a private key of the declared KeyType if the certificate has
+// none. If a key is present it must match KeyType and RSABits.
+func (c *Certificate) EnsureKey() error {
+	if c.PrivateKey != nil {
+		return c.checkKey()
+	}
+	keyType, err := c.keyType()
+	if err != nil || keyType == "" {
+		return err
+	}
+	priv, err := generateKeyType(keyType, c.rsaBits(keyType), c.AllowWeakKey)
+	if err != nil {
+		return err
+	}
+	c.PrivateKey = priv
+	return nil
+}
+
+// keyType returns the declared key type, an RSABits without KeyType declares an RSA key.
+func (c *Certificate) keyType() (string, error) {
+	keyType := c.KeyType
+	if keyType == "" && c.RSABits != 0 {
+		keyType = "rsa"
+	}
+	if keyType == "" {
+		return "", nil
+	}
+	if !isStringInList(keyType, keyTypes) {
+		return "", fmt.Errorf("unknown key type: %q", keyType)
+	}
+	if c.RSABits != 0 {
+		switch keyType {
+		case "rsa":
+		case "rsa2048", "rsa4096":
+			if c.rsaBits(keyType) != c.RSABits {
+				return "", fmt.Errorf("RSABits %d conflicts with key type %s", c.RSABits, keyType)
+			}
+		default:
+			return "", fmt.Errorf("RSABits is only used with RSA keys, not with key type %s", keyType)
+		}
+	}
+	return keyType, nil
+}
+
+// IsKeyType reports whether name is one of the values accepted in Certificate.KeyType.
+func IsKeyType(name string) bool {
+	return isStringInList(name, keyTypes)
+}
+
+// rsaBits returns the length of an RSA key of keyType.
+func (c *Certificate) rsaBits(keyType string) int {
+	switch keyType {
+	case "rsa2048":
+		return 2048
+	case "rsa4096":
+		return 4096
+	}
+	if c.RSABits != 0 {
+		return c.RSABits
+	}
+	return defaultRSABits
+}
+
+func generateKeyType(keyType string, rsaBits int, allowWeak bool) (crypto.Signer, error) {
+	switch keyType {
+	case "rsa", "rsa2048", "rsa4096":
+		return key.Generate(key.KeySpec{Type: "RSA", Bits: rsaBits, AllowWeak: allowWeak})
+	case "ec256":
+		return key.GenerateECDSA("P-256")
+	case "ec384":
+		return key.GenerateECDSA("P-384")
+	case "ec521":
+		return key.GenerateECDSA("P-521")
+	case "ed25519":
+		_, priv, err := ed25519.GenerateKey(key.RandReader)
+		return priv, err
+	default:
+		return nil, fmt.Errorf("unknown key type: %q", keyType)
+	}
+}
+
+// checkKey returns an error if the private key is not of the declared type, without
+// a declared type any key matches.
+func (c *Certificate) checkKey() error {
+	keyType, err := c.keyType()
+	if err != nil || keyType == "" || c.PrivateKey == nil {
+		return err
+	}
+	signer, ok := c.PrivateKey.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("unsupported private key type: %T", c.PrivateKey)
+	}
+	var actual string
+	switch pub := signer.Public().(type) {
+	case *rsa.PublicKey:
+		actual = fmt.Sprintf("rsa%d", pub.N.BitLen())
+		if keyType == "rsa" && c.RSABits == 0 {
+			// any length will do
+			keyType = actual
+		}
+	case *ecdsa.PublicKey:
+		actual = fmt.Sprintf("ec%d", pub.Curve.Params().BitSize)
+	case ed25519.PublicKey:
+		actual = "ed25519"
+	default:
+		actual = fmt.Sprintf("%T", pub)
+	}
+	if keyType == "rsa" {
+		keyType = fmt.Sprintf("rsa%d", c.RSABits)
+	}
+	if actual != keyType {
+		return fmt.Errorf("private key is %s, but key type %s is declared", actual, keyType)
+	}
+	return nil
+}
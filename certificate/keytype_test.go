@@ -0,0 +1,153 @@
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"path/filepath"
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestEnsureKey(t *testing.T) {
+	tests := map[string]func(interface{}) bool{
+		"ec384": func(priv interface{}) bool {
+			k, ok := priv.(*ecdsa.PrivateKey)
+			return ok && k.Curve == elliptic.P384()
+		},
+		"ed25519": func(priv interface{}) bool {
+			_, ok := priv.(ed25519.PrivateKey)
+			return ok
+		},
+	}
+	for keyType, check := range tests {
+		data := Certificate{CommonName: "www.foo.se", KeyType: keyType}
+		if err := data.EnsureKey(); err != nil {
+			t.Fatalf("%s: unexpected error: %v", keyType, err)
+		}
+		if !check(data.PrivateKey) {
+			t.Fatalf("%s: got key %T", keyType, data.PrivateKey)
+		}
+	}
+}
+
+func TestIssueGeneratesKeyOfKeyType(t *testing.T) {
+	issuer := testIssuer(t)
+	der, err := issuer.Issue(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, KeyType: "ec384"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert := parseCert(t, der)
+	if pub, ok := cert.PublicKey.(*ecdsa.PublicKey); !ok || pub.Curve != elliptic.P384() {
+		t.Fatalf("got public key %T, want a P-384 key", cert.PublicKey)
+	}
+	preview, err := PreviewTemplate(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, KeyType: "ed25519"})
+	if err != nil || len(preview.SubjectKeyId) == 0 {
+		t.Fatalf("got: %v, want a preview with a generated key", err)
+	}
+	ca, err := NewCA(Certificate{Id: "ca", CA: true, KeyType: "ec384"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if k, ok := ca.Key.(*ecdsa.PrivateKey); !ok || k.Curve != elliptic.P384() {
+		t.Fatalf("got CA key %T, want a P-384 key", ca.Key)
+	}
+	if _, err := issuer.Issue(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}}); err == nil {
+		t.Fatal("expected error without private key and KeyType")
+	}
+}
+
+func TestTemplateWithoutKey(t *testing.T) {
+	// NewCertificateTemplate does not generate the key, its copy of data would be lost
+	for _, data := range []Certificate{{Id: "one"}, {Id: "one", KeyType: "ec256"}} {
+		if _, err := NewCertificateTemplate(data); err == nil {
+			t.Fatalf("KeyType %q: expected an error for a certificate without a private key", data.KeyType)
+		}
+	}
+}
+
+func TestEnsureKeyMismatch(t *testing.T) {
+	data := Certificate{CommonName: "www.foo.se", KeyType: "ec384", PrivateKey: key.GenerateKey("P256", 0)}
+	if err := data.EnsureKey(); err == nil {
+		t.Fatal("expected error for a P-256 key declared as ec384")
+	}
+	if _, err := NewCertificateTemplate(data); err == nil {
+		t.Fatal("template created with a key of another type")
+	}
+	data = Certificate{CommonName: "www.foo.se", KeyType: "ec224"}
+	if err := data.EnsureKey(); err == nil {
+		t.Fatal("expected error for an unknown key type")
+	}
+}
+
+func TestBootstrapPKIKeyTypes(t *testing.T) {
+	dir := t.TempDir()
+	err := BootstrapPKI(dir,
+		Certificate{CommonName: "root", KeyType: "ec521"},
+		Certificate{CommonName: "intermediate", KeyType: "ec384"},
+		Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, KeyType: "ed25519"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	inter, _ := x509.ParseCertificate(readPemAsDER(t, filepath.Join(dir, "intermediate.pem")))
+	if pub, ok := inter.PublicKey.(*ecdsa.PublicKey); !ok || pub.Curve != elliptic.P384() {
+		t.Fatalf("got intermediate key %T, want P-384", inter.PublicKey)
+	}
+	leaf, _ := x509.ParseCertificate(readPemAsDER(t, filepath.Join(dir, "leaf.pem")))
+	if _, ok := leaf.PublicKey.(ed25519.PublicKey); !ok {
+		t.Fatalf("got leaf key %T, want Ed25519", leaf.PublicKey)
+	}
+}
+
+func TestEnsureKeyRSABits(t *testing.T) {
+	data := Certificate{CommonName: "www.foo.se", RSABits: 1024}
+	if err := data.EnsureKey(); err == nil {
+		t.Fatal("expected error for a 1024 bit key without AllowWeakKey")
+	}
+	data.AllowWeakKey = true
+	if err := data.EnsureKey(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if k, ok := data.PrivateKey.(*rsa.PrivateKey); !ok || k.N.BitLen() != 1024 {
+		t.Fatalf("got key %T, want RSA 1024", data.PrivateKey)
+	}
+	data = Certificate{CommonName: "www.foo.se", KeyType: "rsa"}
+	if err := data.EnsureKey(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if k := data.PrivateKey.(*rsa.PrivateKey); k.N.BitLen() != 2048 {
+		t.Fatalf("got %d bits, want the default 2048", k.N.BitLen())
+	}
+	data = Certificate{CommonName: "www.foo.se", KeyType: "rsa2048", RSABits: 4096}
+	if err := data.EnsureKey(); err == nil {
+		t.Fatal("expected error for RSABits conflicting with the key type")
+	}
+	data = Certificate{CommonName: "www.foo.se", KeyType: "ec256", RSABits: 4096}
+	if err := data.EnsureKey(); err == nil {
+		t.Fatal("expected error for RSABits with a non RSA key type")
+	}
+	data = Certificate{CommonName: "www.foo.se", RSABits: 2048, PrivateKey: key.GenerateKey("RSA", 1024)}
+	if err := data.EnsureKey(); err == nil {
+		t.Fatal("expected error for a provided key of another length")
+	}
+}
+
+func TestBootstrapPKIRSABits(t *testing.T) {
+	dir := t.TempDir()
+	err := BootstrapPKI(dir,
+		Certificate{CommonName: "root", RSABits: 2048},
+		Certificate{CommonName: "intermediate"},
+		Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, RSABits: 1024, AllowWeakKey: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, bits := range map[string]int{"ca.pem": 2048, "leaf.pem": 1024} {
+		cert, _ := x509.ParseCertificate(readPemAsDER(t, filepath.Join(dir, name)))
+		if pub, ok := cert.PublicKey.(*rsa.PublicKey); !ok || pub.N.BitLen() != bits {
+			t.Fatalf("%s: got key %T, want RSA %d", name, cert.PublicKey, bits)
+		}
+	}
+}
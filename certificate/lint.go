@@ -0,0 +1,208 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+// Codes of the violations reported by LintTemplate.
+const (
+	LintWeakRSAKey      = "weak-rsa-key"
+	LintWeakCurve       = "weak-ecdsa-curve"
+	LintSHA1Signature   = "sha1-signature"
+	LintInvalidValidity = "invalid-validity"
+	LintLeafValidity    = "leaf-validity-too-long"
+	LintFutureNotBefore = "not-before-in-future"
+)
+
+// Codes of the findings reported by Lint.
+const (
+	LintMissingSAN          = "missing-san"
+	LintCNNotInSAN          = "cn-not-in-san"
+	LintCAWithoutCertSign   = "ca-without-certsign"
+	LintLeafWithCertSign    = "leaf-with-certsign"
+	LintMissingSKID         = "missing-skid"
+	LintMissingAKID         = "missing-akid"
+	LintSANNotCritical      = "empty-subject-san-not-critical"
+	LintNonPositiveSerial   = "non-positive-serial"
+	LintDeprecatedSignature = "deprecated-signature-algorithm"
+)
+
+// Severity tells how serious a Finding is.
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Finding is a problem found by Lint in an issued certificate.
+type Finding struct {
+	Code     string
+	Severity Severity
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s: %s", f.Severity, f.Code, f.Message)
+}
+
+var oidSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+
+const (
+	// DefaultMaxLeafValidity is the longest validity browsers accept for TLS server certificates.
+	DefaultMaxLeafValidity = 398 * 24 * time.Hour
+	// maxFutureNotBefore is how far ahead NotBefore may be before TLS stacks start to trip on it.
+	maxFutureNotBefore = 48 * time.Hour
+)
+
+// Violation is a policy violation found by LintTemplate. Code is stable and can be
+// used to allow specific violations.
+type Violation struct {
+	Code    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return v.Code + ": " + v.Message
+}
+
+// LintError is returned when a certificate is not issued because of policy violations.
+type LintError struct {
+	Violations []Violation
+}
+
+func (e *LintError) Error() string {
+	var msgs []string
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.String())
+	}
+	return "certificate violates policy: " + strings.Join(msgs, ", ")
+}
+
+// LintTemplate checks a certificate template and the public key it is issued for
+// against a minimal policy: RSA keys of at least key.MinRSAKeyBits, ECDSA curves of at least
+// P-256, no SHA-1 signatures, a NotAfter after NotBefore and a NotBefore at most 48
+// hours ahead. Server certificates that are not CAs may be valid for at most
+// DefaultMaxLeafValidity. All violations are returned.
+func LintTemplate(cert *x509.Certificate, pub interface{}) []Violation {
+	return lintTemplate(cert, pub, DefaultMaxLeafValidity, time.Now())
+}
+
+// lintTemplate is LintTemplate with the longest validity allowed for server certificates.
+func lintTemplate(cert *x509.Certificate, pub interface{}, maxLeafValidity time.Duration, now time.Time) []Violation {
+	var violations []Violation
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if err := checkRSAKeyBits(k, key.MinRSAKeyBits); err != nil {
+			violations = append(violations, Violation{LintWeakRSAKey, err.Error()})
+		}
+	case *ecdsa.PublicKey:
+		if k.Curve.Params().BitSize < 256 {
+			violations = append(violations, Violation{LintWeakCurve, fmt.Sprintf("curve %s is weaker than P-256", k.Curve.Params().Name)})
+		}
+	}
+	switch cert.SignatureAlgorithm {
+	case x509.SHA1WithRSA, x509.ECDSAWithSHA1, x509.DSAWithSHA1:
+		violations = append(violations, Violation{LintSHA1Signature, fmt.Sprintf("signature algorithm %v uses SHA-1", cert.SignatureAlgorithm)})
+	}
+	if !cert.NotAfter.After(cert.NotBefore) {
+		violations = append(violations, Violation{LintInvalidValidity, fmt.Sprintf("NotAfter %v is not after NotBefore %v", cert.NotAfter, cert.NotBefore)})
+	}
+	if validity := cert.NotAfter.Sub(cert.NotBefore); !cert.IsCA && isServerCertificate(cert) && validity > maxLeafValidity {
+		violations = append(violations, Violation{LintLeafValidity, fmt.Sprintf("server certificate is valid for %v, want at most %v", validity, maxLeafValidity)})
+	}
+	if cert.NotBefore.After(now.Add(maxFutureNotBefore)) {
+		violations = append(violations, Violation{LintFutureNotBefore, fmt.Sprintf("NotBefore %v is more than %v ahead", cert.NotBefore, maxFutureNotBefore)})
+	}
+	return violations
+}
+
+// Lint audits an issued DER encoded certificate for the problems this package
+// is able to produce: missing or incomplete SANs, wrong certsign usage, missing key
+// identifiers, an empty subject without a critical SAN, a non positive serial and
+// deprecated signature algorithms.
+func Lint(der []byte) ([]Finding, error) {
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %v", err)
+	}
+	var findings []Finding
+	add := func(code string, severity Severity, format string, args ...interface{}) {
+		findings = append(findings, Finding{Code: code, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+	hasSAN := len(cert.DNSNames)+len(cert.IPAddresses)+len(cert.EmailAddresses)+len(cert.URIs) > 0
+	if !cert.IsCA {
+		if !hasSAN {
+			add(LintMissingSAN, SeverityError, "certificate has no subject alternative names")
+		} else if cn := cert.Subject.CommonName; cn != "" && !isStringInList("DNS:"+cn, AllSANs(cert)) && !isStringInList("IP:"+cn, AllSANs(cert)) {
+			add(LintCNNotInSAN, SeverityWarning, "common name %q is not one of the subject alternative names", cn)
+		}
+	}
+	if cert.IsCA && cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		add(LintCAWithoutCertSign, SeverityError, "CA certificate lacks the certsign key usage")
+	}
+	if !cert.IsCA && cert.KeyUsage&x509.KeyUsageCertSign != 0 {
+		add(LintLeafWithCertSign, SeverityError, "certificate is not a CA but has the certsign key usage")
+	}
+	if len(cert.SubjectKeyId) == 0 {
+		severity := SeverityWarning
+		if cert.IsCA {
+			severity = SeverityError
+		}
+		add(LintMissingSKID, severity, "certificate has no subject key identifier")
+	}
+	if len(cert.AuthorityKeyId) == 0 && !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		add(LintMissingAKID, SeverityError, "certificate has no authority key identifier")
+	}
+	if len(cert.Subject.Names) == 0 {
+		for _, ext := range cert.Extensions {
+			if ext.Id.Equal(oidSubjectAltName) && !ext.Critical {
+				add(LintSANNotCritical, SeverityError, "subject is empty but the subject alternative name extension is not critical")
+			}
+		}
+	}
+	if cert.SerialNumber.Sign() <= 0 {
+		add(LintNonPositiveSerial, SeverityError, "serial number %v is not positive", cert.SerialNumber)
+	}
+	switch cert.SignatureAlgorithm {
+	case x509.MD2WithRSA, x509.MD5WithRSA, x509.SHA1WithRSA, x509.ECDSAWithSHA1, x509.DSAWithSHA1:
+		add(LintDeprecatedSignature, SeverityError, "signature algorithm %v is deprecated", cert.SignatureAlgorithm)
+	}
+	return findings, nil
+}
+
+func isServerCertificate(cert *x509.Certificate) bool {
+	for _, eku := range cert.ExtKeyUsage {
+		if eku == x509.ExtKeyUsageServerAuth {
+			return true
+		}
+	}
+	return false
+}
+
+// filterViolations removes the violations with an allowed code.
+func filterViolations(violations []Violation, allowed []string) []Violation {
+	var kept []Violation
+	for _, v := range violations {
+		if !isStringInList(v.Code, allowed) {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
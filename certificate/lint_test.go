@@ -0,0 +1,214 @@
+package certificate
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func violationCodes(violations []Violation) []string {
+	var codes []string
+	for _, v := range violations {
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
+
+func TestLintTemplate(t *testing.T) {
+	now := time.Now()
+	valid := &x509.Certificate{NotBefore: now, NotAfter: now.Add(time.Hour), SignatureAlgorithm: x509.SHA256WithRSA}
+	if v := LintTemplate(valid, key.PublicKey(key.GenerateKey("P256", 0))); len(v) != 0 {
+		t.Fatalf("got: %v, want no violations", v)
+	}
+	weak := &x509.Certificate{NotBefore: now, NotAfter: now.Add(-time.Hour), SignatureAlgorithm: x509.SHA1WithRSA}
+	codes := violationCodes(LintTemplate(weak, key.PublicKey(key.GenerateKey("RSA", 1024))))
+	want := []string{LintWeakRSAKey, LintSHA1Signature, LintInvalidValidity}
+	if len(codes) != len(want) {
+		t.Fatalf("got: %v, want %v", codes, want)
+	}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("got: %v, want %v", codes, want)
+		}
+	}
+	codes = violationCodes(LintTemplate(valid, key.PublicKey(key.GenerateKey("P224", 0))))
+	if len(codes) != 1 || codes[0] != LintWeakCurve {
+		t.Fatalf("got: %v, want %s", codes, LintWeakCurve)
+	}
+}
+
+func TestIssuerStrict(t *testing.T) {
+	issuer := testIssuer(t)
+	issuer.Strict = true
+	leaf := Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: key.GenerateKey("P224", 0), SignatureAlg: "SHA1", AllowInsecureSHA1: true}
+	_, err := issuer.Issue(leaf)
+	var lintErr *LintError
+	if !errors.As(err, &lintErr) || len(lintErr.Violations) != 2 {
+		t.Fatalf("got: %v, want two violations", err)
+	}
+	issuer.AllowedViolations = []string{LintWeakCurve}
+	if _, err := issuer.Issue(leaf); !errors.As(err, &lintErr) || len(lintErr.Violations) != 1 || lintErr.Violations[0].Code != LintSHA1Signature {
+		t.Fatalf("got: %v, want only %s", err, LintSHA1Signature)
+	}
+	leaf.SignatureAlg = "SHA256"
+	if _, err := issuer.Issue(leaf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLintTemplateValidity(t *testing.T) {
+	now := time.Now()
+	pub := key.PublicKey(key.GenerateKey("P256", 0))
+	server := &x509.Certificate{NotBefore: now, NotAfter: now.AddDate(10, 0, 0), ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}
+	if codes := violationCodes(LintTemplate(server, pub)); len(codes) != 1 || codes[0] != LintLeafValidity {
+		t.Fatalf("got: %v, want %s", codes, LintLeafValidity)
+	}
+	ca := &x509.Certificate{NotBefore: now, NotAfter: now.AddDate(10, 0, 0), IsCA: true, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}
+	if v := LintTemplate(ca, pub); len(v) != 0 {
+		t.Fatalf("got: %v, want no violations for a CA", v)
+	}
+	client := &x509.Certificate{NotBefore: now, NotAfter: now.AddDate(10, 0, 0), ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}
+	if v := LintTemplate(client, pub); len(v) != 0 {
+		t.Fatalf("got: %v, want no violations for a client certificate", v)
+	}
+	future := &x509.Certificate{NotBefore: now.Add(72 * time.Hour), NotAfter: now.AddDate(0, 1, 0)}
+	if codes := violationCodes(LintTemplate(future, pub)); len(codes) != 1 || codes[0] != LintFutureNotBefore {
+		t.Fatalf("got: %v, want %s", codes, LintFutureNotBefore)
+	}
+}
+
+func TestIssuerMaxLeafValidity(t *testing.T) {
+	issuer := testIssuer(t)
+	issuer.Strict = true
+	now := time.Now()
+	leaf := Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: key.GenerateKey("P256", 0), ValidFrom: now, ValidTo: now.AddDate(0, 0, 90)}
+	if _, err := issuer.Issue(leaf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	issuer.MaxLeafValidity = 30 * 24 * time.Hour
+	var lintErr *LintError
+	if _, err := issuer.Issue(leaf); !errors.As(err, &lintErr) || lintErr.Violations[0].Code != LintLeafValidity {
+		t.Fatalf("got: %v, want %s", err, LintLeafValidity)
+	}
+}
+
+// lintCert signs template directly with x509.CreateCertificate so certificates this
+// package would never produce can be built.
+func lintCert(t *testing.T, template, parent *x509.Certificate, parentPriv interface{}) []byte {
+	priv := key.GenerateKey("P256", 0)
+	if parent == nil {
+		parent, parentPriv = template, priv
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.PublicKey(priv), parentPriv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return der
+}
+
+func TestLint(t *testing.T) {
+	now := time.Now()
+	caPriv := key.GenerateKey("P256", 0)
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             now,
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, key.PublicKey(caPriv), caPriv)
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	ca, _ = x509.ParseCertificate(caDER)
+	leaf := func() *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      pkix.Name{CommonName: "www.foo.se"},
+			DNSNames:     []string{"www.foo.se"},
+			NotBefore:    now,
+			NotAfter:     now.Add(time.Hour),
+			SubjectKeyId: []byte{1, 2, 3},
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+		}
+	}
+	sanExt, _ := asn1.Marshal([]asn1.RawValue{{Class: asn1.ClassContextSpecific, Tag: 2, Bytes: []byte("www.foo.se")}})
+	tests := map[string]struct {
+		der  []byte
+		want []string
+	}{
+		"valid leaf": {lintCert(t, leaf(), ca, caPriv), nil},
+		"valid ca":   {caDER, nil},
+		"missing san": {func() []byte {
+			c := leaf()
+			c.DNSNames = nil
+			return lintCert(t, c, ca, caPriv)
+		}(), []string{LintMissingSAN}},
+		"cn not in san": {func() []byte {
+			c := leaf()
+			c.DNSNames = []string{"www.bar.se"}
+			return lintCert(t, c, ca, caPriv)
+		}(), []string{LintCNNotInSAN}},
+		"ca without certsign": {func() []byte {
+			c := &x509.Certificate{SerialNumber: big.NewInt(3), Subject: pkix.Name{CommonName: "ca"}, NotBefore: now, NotAfter: now.Add(time.Hour),
+				IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCRLSign}
+			return lintCert(t, c, nil, nil)
+		}(), []string{LintCAWithoutCertSign}},
+		"leaf with certsign and zero serial": {func() []byte {
+			c := leaf()
+			c.SerialNumber = big.NewInt(0)
+			c.KeyUsage |= x509.KeyUsageCertSign
+			return lintCert(t, c, ca, caPriv)
+		}(), []string{LintLeafWithCertSign, LintNonPositiveSerial}},
+		"missing key identifiers": {func() []byte {
+			c := leaf()
+			c.SubjectKeyId = nil
+			parent := *ca
+			parent.SubjectKeyId = nil
+			return lintCert(t, c, &parent, caPriv)
+		}(), []string{LintMissingSKID, LintMissingAKID}},
+		"empty subject with non critical san": {func() []byte {
+			c := leaf()
+			c.Subject = pkix.Name{}
+			c.DNSNames = nil
+			c.ExtraExtensions = []pkix.Extension{{Id: oidSubjectAltName, Critical: false, Value: sanExt}}
+			return lintCert(t, c, ca, caPriv)
+		}(), []string{LintSANNotCritical}},
+	}
+	for name, test := range tests {
+		findings, err := Lint(test.der)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		var codes []string
+		for _, f := range findings {
+			codes = append(codes, f.Code)
+		}
+		if !reflect.DeepEqual(codes, test.want) {
+			t.Fatalf("%s: got: %v, want %v", name, codes, test.want)
+		}
+	}
+}
+
+func TestLintDeprecatedSignature(t *testing.T) {
+	priv := key.GenerateKey("RSA", 1024)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), DNSNames: []string{"www.foo.se"}, SubjectKeyId: []byte{1}, SignatureAlgorithm: x509.SHA1WithRSA}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.PublicKey(priv), priv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	findings, _ := Lint(der)
+	if len(findings) != 1 || findings[0].Code != LintDeprecatedSignature || findings[0].Severity != SeverityError {
+		t.Fatalf("got: %v, want %s", findings, LintDeprecatedSignature)
+	}
+}
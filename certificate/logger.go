@@ -0,0 +1,23 @@
+package certificate
+
+// Logger receives the messages of the package, such as warnings about weak hash and
+// curve combinations and the outcome of CheckCertificate. A *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// nopLogger drops all messages, the package is silent until SetLogger is called.
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...interface{}) {}
+
+var logger Logger = nopLogger{}
+
+// SetLogger sets where the package writes its messages, nil silences it again.
+// Like RandReader it must only be changed before certificates are created.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = nopLogger{}
+	}
+	logger = l
+}
@@ -0,0 +1,117 @@
+package certificate
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// ErrOCSPUnavailable is returned then the OCSP responder could not be reached or did
+// not give an answer, callers that soft-fail can accept the certificate on this error.
+var ErrOCSPUnavailable = errors.New("OCSP responder unavailable")
+
+// RevokedError is returned by CheckOCSP for a revoked certificate.
+type RevokedError struct {
+	SerialNumber *big.Int
+	RevokedAt    time.Time
+	Reason       RevocationReason
+}
+
+func (e *RevokedError) Error() string {
+	return fmt.Sprintf("certificate with serial %v was revoked at %v: %v", e.SerialNumber, e.RevokedAt, e.Reason)
+}
+
+const (
+	// maxOCSPGetLength is the longest GET request URL responders must accept, see RFC 5019.
+	maxOCSPGetLength = 255
+	// ocspClockSkew is how much the thisUpdate and nextUpdate of a response may be off.
+	ocspClockSkew = 5 * time.Minute
+)
+
+// CheckOCSP asks the OCSP responder for the revocation status of leaf, issued by
+// issuer. responderURL overrides the responder in the AIA extension of leaf. Short
+// requests are sent with GET, longer ones with POST. The signature of the response
+// and its thisUpdate to nextUpdate window are checked. A revoked certificate gives a
+// *RevokedError together with the response, a responder that can not be reached or
+// does not answer gives an error wrapping ErrOCSPUnavailable.
+func CheckOCSP(ctx context.Context, leaf, issuer *x509.Certificate, responderURL string) (*ocsp.Response, error) {
+	if responderURL == "" {
+		if len(leaf.OCSPServer) == 0 {
+			return nil, fmt.Errorf("certificate %v has no OCSP responder", leaf.Subject)
+		}
+		responderURL = leaf.OCSPServer[0]
+	}
+	request, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OCSP request: %v", err)
+	}
+	body, err := postOrGetOCSP(ctx, responderURL, request)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ocsp.ParseResponseForCert(body, leaf, issuer)
+	if err != nil {
+		var respErr ocsp.ResponseError
+		if errors.As(err, &respErr) && (respErr.Status == ocsp.TryLater || respErr.Status == ocsp.InternalError) {
+			return nil, fmt.Errorf("%w: %s: %v", ErrOCSPUnavailable, responderURL, err)
+		}
+		return nil, fmt.Errorf("invalid OCSP response from %s: %v", responderURL, err)
+	}
+	now := time.Now()
+	if resp.ThisUpdate.After(now.Add(ocspClockSkew)) {
+		return nil, fmt.Errorf("OCSP response from %s is not valid before %v", responderURL, resp.ThisUpdate)
+	}
+	if !resp.NextUpdate.IsZero() && resp.NextUpdate.Before(now.Add(-ocspClockSkew)) {
+		return nil, fmt.Errorf("OCSP response from %s expired at %v", responderURL, resp.NextUpdate)
+	}
+	switch resp.Status {
+	case ocsp.Good:
+		return resp, nil
+	case ocsp.Revoked:
+		return resp, &RevokedError{SerialNumber: resp.SerialNumber, RevokedAt: resp.RevokedAt, Reason: RevocationReason(resp.RevocationReason)}
+	default:
+		return resp, fmt.Errorf("OCSP responder %s does not know certificate with serial %v", responderURL, leaf.SerialNumber)
+	}
+}
+
+// postOrGetOCSP sends the DER encoded request and returns the body of the answer.
+func postOrGetOCSP(ctx context.Context, responderURL string, request []byte) ([]byte, error) {
+	getURL := strings.TrimSuffix(responderURL, "/") + "/" + url.PathEscape(base64.StdEncoding.EncodeToString(request))
+	var httpReq *http.Request
+	var err error
+	if len(getURL) <= maxOCSPGetLength {
+		httpReq, err = http.NewRequestWithContext(ctx, http.MethodGet, getURL, nil)
+	} else {
+		httpReq, err = http.NewRequestWithContext(ctx, http.MethodPost, responderURL, bytes.NewReader(request))
+		if err == nil {
+			httpReq.Header.Set("Content-Type", "application/ocsp-request")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid OCSP responder URL %s: %v", responderURL, err)
+	}
+	httpResp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrOCSPUnavailable, responderURL, err)
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s: %s", ErrOCSPUnavailable, responderURL, httpResp.Status)
+	}
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrOCSPUnavailable, responderURL, err)
+	}
+	return body, nil
+}
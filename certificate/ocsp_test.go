@@ -0,0 +1,116 @@
+package certificate
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+	"golang.org/x/crypto/ocsp"
+)
+
+// ocspResponder answers every request with template, signed by the issuer.
+func ocspResponder(t *testing.T, issuer *Issuer, template ocsp.Response, methods *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*methods = append(*methods, r.Method)
+		var der []byte
+		var err error
+		if r.Method == http.MethodGet {
+			// the base64 request may contain slashes
+			der, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(r.URL.Path, "/"))
+		} else {
+			der, err = io.ReadAll(r.Body)
+		}
+		if err != nil {
+			t.Errorf("failed to read request: %v", err)
+			return
+		}
+		req, err := ocsp.ParseRequest(der)
+		if err != nil {
+			t.Errorf("failed to parse request: %v", err)
+			return
+		}
+		template.SerialNumber = req.SerialNumber
+		resp, err := ocsp.CreateResponse(issuer.Cert, issuer.Cert, template, issuer.Key.(crypto.Signer))
+		if err != nil {
+			t.Errorf("failed to create response: %v", err)
+			return
+		}
+		w.Write(resp)
+	}))
+}
+
+func ocspLeaf(t *testing.T, issuer *Issuer) *x509.Certificate {
+	der, err := issuer.Issue(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: key.GenerateKey("P256", 0)})
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	return parseCert(t, der)
+}
+
+func TestCheckOCSP(t *testing.T) {
+	issuer := testIssuer(t)
+	leaf := ocspLeaf(t, issuer)
+	now := time.Now()
+	var methods []string
+	good := ocspResponder(t, issuer, ocsp.Response{Status: ocsp.Good, ThisUpdate: now, NextUpdate: now.Add(time.Hour)}, &methods)
+	defer good.Close()
+	resp, err := CheckOCSP(context.Background(), leaf, issuer.Cert, good.URL)
+	if err != nil || resp.Status != ocsp.Good {
+		t.Fatalf("got: %v, want a good response", err)
+	}
+	// too long for GET
+	if _, err := CheckOCSP(context.Background(), leaf, issuer.Cert, good.URL+"/"+strings.Repeat("a", 200)); err != nil {
+		t.Fatalf("unexpected error with POST: %v", err)
+	}
+	if len(methods) != 2 || methods[0] != http.MethodGet || methods[1] != http.MethodPost {
+		t.Fatalf("got methods %v, want GET then POST", methods)
+	}
+
+	revoked := ocspResponder(t, issuer, ocsp.Response{Status: ocsp.Revoked, RevokedAt: now.Add(-time.Hour), RevocationReason: ocsp.KeyCompromise, ThisUpdate: now, NextUpdate: now.Add(time.Hour)}, &methods)
+	defer revoked.Close()
+	_, err = CheckOCSP(context.Background(), leaf, issuer.Cert, revoked.URL)
+	var revokedErr *RevokedError
+	if !errors.As(err, &revokedErr) || revokedErr.Reason != ReasonKeyCompromise || errors.Is(err, ErrOCSPUnavailable) {
+		t.Fatalf("got: %v, want a revoked error for keyCompromise", err)
+	}
+}
+
+func TestCheckOCSPInvalidResponses(t *testing.T) {
+	issuer := testIssuer(t)
+	leaf := ocspLeaf(t, issuer)
+	now := time.Now()
+	var methods []string
+	expired := ocspResponder(t, issuer, ocsp.Response{Status: ocsp.Good, ThisUpdate: now.Add(-2 * time.Hour), NextUpdate: now.Add(-time.Hour)}, &methods)
+	defer expired.Close()
+	if _, err := CheckOCSP(context.Background(), leaf, issuer.Cert, expired.URL); err == nil || errors.Is(err, ErrOCSPUnavailable) {
+		t.Fatalf("got: %v, want an expired response error", err)
+	}
+	other := testIssuer(t)
+	forged := ocspResponder(t, other, ocsp.Response{Status: ocsp.Good, ThisUpdate: now, NextUpdate: now.Add(time.Hour)}, &methods)
+	defer forged.Close()
+	if _, err := CheckOCSP(context.Background(), leaf, issuer.Cert, forged.URL); err == nil {
+		t.Fatal("expected a response signed by another CA to fail")
+	}
+}
+
+func TestCheckOCSPUnavailable(t *testing.T) {
+	issuer := testIssuer(t)
+	leaf := ocspLeaf(t, issuer)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	if _, err := CheckOCSP(context.Background(), leaf, issuer.Cert, down.URL); !errors.Is(err, ErrOCSPUnavailable) {
+		t.Fatalf("got: %v, want ErrOCSPUnavailable", err)
+	}
+	if _, err := CheckOCSP(context.Background(), leaf, issuer.Cert, ""); err == nil {
+		t.Fatal("expected error for a certificate without OCSP responder")
+	}
+}
@@ -0,0 +1,104 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+// PEMToDER returns the DER bytes of the first PEM block, which must be a CERTIFICATE.
+// Leading whitespace, also on the line of the BEGIN marker, is ignored.
+func PEMToDER(pemBytes []byte) ([]byte, error) {
+	block, _ := pem.Decode(bytes.TrimLeftFunc(pemBytes, unicode.IsSpace))
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("expected CERTIFICATE PEM block, got: %s", block.Type)
+	}
+	return block.Bytes, nil
+}
+
+// DERToPEM returns the DER encoded certificate as a PEM CERTIFICATE block.
+func DERToPEM(der []byte) []byte {
+	return encodeCertificatePem(der)
+}
+
+// ParseCertificatesPem parses every CERTIFICATE block in data, such as a ca-bundle.pem,
+// other block types are skipped. The certificates that parse are always returned, the
+// error names the index and parse error of every block that did not.
+func ParseCertificatesPem(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	var errs []error
+	for i := 0; ; i++ {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("block %d: %v", i, err))
+			continue
+		}
+		certs = append(certs, cert)
+	}
+	if len(errs) > 0 {
+		return certs, fmt.Errorf("failed to parse certificates: %v", errors.Join(errs...))
+	}
+	return certs, nil
+}
+
+// BundleOrderError is returned by ValidateBundleOrder for a bundle in the wrong order.
+type BundleOrderError struct {
+	// Position is the index of the first certificate not issued by the one after it.
+	Position int
+	Subject  string
+	Issuer   string
+	Next     string
+}
+
+func (e *BundleOrderError) Error() string {
+	return fmt.Sprintf("bundle out of order at certificate %d: %s is issued by %s, but the next certificate is %s", e.Position, e.Subject, e.Issuer, e.Next)
+}
+
+// ValidateBundleOrder checks that the certificates in the PEM bundle are ordered from
+// leaf to root, with the issuer of every certificate being the subject of the next.
+// The first certificate out of order is reported as a *BundleOrderError.
+func ValidateBundleOrder(pemBytes []byte) error {
+	certs, err := ParseCertificatesPem(pemBytes)
+	if err != nil {
+		return err
+	}
+	if len(certs) == 0 {
+		return errors.New("no certificates found in bundle")
+	}
+	for i := 0; i < len(certs)-1; i++ {
+		if !bytes.Equal(certs[i].RawIssuer, certs[i+1].RawSubject) {
+			return &BundleOrderError{
+				Position: i,
+				Subject:  certs[i].Subject.String(),
+				Issuer:   certs[i].Issuer.String(),
+				Next:     certs[i+1].Subject.String(),
+			}
+		}
+	}
+	return nil
+}
+
+// isPem reports whether data looks like PEM rather than DER.
+func isPem(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeftFunc(data, unicode.IsSpace), []byte("-----BEGIN"))
+}
+
+// PublicKeyPem returns the SubjectPublicKeyInfo of cert as a PEM PUBLIC KEY block, the
+// same as openssl x509 -pubkey prints.
+func PublicKeyPem(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: cert.RawSubjectPublicKeyInfo})
+}
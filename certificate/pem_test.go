@@ -0,0 +1,113 @@
+package certificate
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestPEMToDERRoundTrip(t *testing.T) {
+	der, err := PEMToDER([]byte("\n  \t" + opensslCertPem))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert, err := parseCertificatePem([]byte(opensslCertPem))
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	if !bytes.Equal(der, cert.Raw) {
+		t.Fatal("DER differs from the parsed certificate")
+	}
+	back, err := PEMToDER(DERToPEM(der))
+	if err != nil || !bytes.Equal(back, der) {
+		t.Fatalf("round trip through PEM failed: %v", err)
+	}
+}
+
+func TestPEMToDERInvalid(t *testing.T) {
+	if _, err := PEMToDER([]byte("not pem")); err == nil {
+		t.Fatal("expected error for data without PEM")
+	}
+	if _, err := PEMToDER([]byte(pemPublicKey)); err == nil {
+		t.Fatal("expected error for a non CERTIFICATE block")
+	}
+}
+
+func TestParseCertificatesPem(t *testing.T) {
+	bad := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")}))
+	bundle := opensslCertPem + pemPublicKey + bad + opensslCertPem
+	certs, err := ParseCertificatesPem([]byte(bundle))
+	if len(certs) != 2 {
+		t.Fatalf("got %d certificates, want 2", len(certs))
+	}
+	if err == nil || !strings.Contains(err.Error(), "block 2") {
+		t.Fatalf("got: %v, want error for block 2", err)
+	}
+	if _, err := ParseCertificatesPem([]byte(opensslCertPem + opensslCertPem)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckCertificatePemBundle(t *testing.T) {
+	dir := t.TempDir()
+	if err := BootstrapPKI(dir, Certificate{CommonName: "root"}, Certificate{CommonName: "intermediate"}, Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}}); err != nil {
+		t.Fatalf("failed to bootstrap: %v", err)
+	}
+	interPem, _ := os.ReadFile(filepath.Join(dir, "intermediate.pem"))
+	bundle := append([]byte(opensslCertPem), interPem...)
+	caBytes := readPemAsDER(t, filepath.Join(dir, "ca.pem"))
+	leafBytes := readPemAsDER(t, filepath.Join(dir, "leaf.pem"))
+	if !CheckCertificate("www.foo.se", caBytes, bundle, leafBytes) {
+		t.Fatal("certificate does not verify with a PEM intermediate bundle")
+	}
+}
+
+// golden value from openssl x509 -pubkey -noout
+var opensslCertPublicKeyPem = `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEteEAtPfe6N2NKwDdzd3gji+JPwMV
+4En0MXKhvEGJKwCs2jp1yuaXqcNzFxNd6PbMbrYi9aPbRKOIPtcRP2czJw==
+-----END PUBLIC KEY-----
+`
+
+func TestPublicKeyPem(t *testing.T) {
+	cert, err := parseCertificatePem([]byte(opensslCertPem))
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	if got := string(PublicKeyPem(cert)); got != opensslCertPublicKeyPem {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, opensslCertPublicKeyPem)
+	}
+}
+
+func TestValidateBundleOrder(t *testing.T) {
+	ca, caPriv := createCA()
+	caBytes := Sign(ca, ca, key.PublicKey(caPriv), caPriv)
+	interCa, interCaPriv := createInterCA()
+	interCaBytes := Sign(interCa, ca, key.PublicKey(interCaPriv), caPriv)
+	client, clientPriv := createClient()
+	clientBytes := Sign(client, interCa, key.PublicKey(clientPriv), interCaPriv)
+
+	ordered := bytes.Join([][]byte{DERToPEM(clientBytes), DERToPEM(interCaBytes), DERToPEM(caBytes)}, nil)
+	if err := ValidateBundleOrder(ordered); err != nil {
+		t.Fatalf("unexpected error for an ordered bundle: %v", err)
+	}
+	reversed := bytes.Join([][]byte{DERToPEM(caBytes), DERToPEM(interCaBytes), DERToPEM(clientBytes)}, nil)
+	err := ValidateBundleOrder(reversed)
+	var orderErr *BundleOrderError
+	if !errors.As(err, &orderErr) || orderErr.Position != 0 {
+		t.Fatalf("got: %v, want out of order at certificate 0", err)
+	}
+	swapped := bytes.Join([][]byte{DERToPEM(clientBytes), DERToPEM(caBytes), DERToPEM(interCaBytes)}, nil)
+	if err := ValidateBundleOrder(swapped); !errors.As(err, &orderErr) || orderErr.Position != 0 {
+		t.Fatalf("got: %v, want out of order at certificate 0", err)
+	}
+	if err := ValidateBundleOrder([]byte("not pem")); err == nil {
+		t.Fatal("expected error for an empty bundle")
+	}
+}
@@ -0,0 +1,69 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	oidPKCS7Data       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidPKCS7SignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+)
+
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	// Content is the [0] EXPLICIT wrapped content, if any.
+	Content asn1.RawValue `asn1:"optional"`
+}
+
+// pkcs7SignedData is the SignedData of RFC 2315 without signers, a certificates-only bundle.
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      pkcs7ContentInfo
+	Certificates     asn1.RawValue
+	SignerInfos      asn1.RawValue
+}
+
+// EncodePKCS7 returns the DER encoded certificates as a degenerate PKCS#7 SignedData
+// structure without signatures, the .p7b format read by Windows and Java tools and
+// by openssl pkcs7 -print_certs. The order of the certificates is kept.
+func EncodePKCS7(certs ...[]byte) ([]byte, error) {
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates to encode")
+	}
+	var raw []byte
+	for i, der := range certs {
+		if _, err := x509.ParseCertificate(der); err != nil {
+			return nil, fmt.Errorf("certificate %d: %v", i, err)
+		}
+		raw = append(raw, der...)
+	}
+	emptySet := asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: []byte{}}
+	signedData, err := asn1.Marshal(pkcs7SignedData{
+		Version:          1,
+		DigestAlgorithms: emptySet,
+		ContentInfo:      pkcs7ContentInfo{ContentType: oidPKCS7Data},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw},
+		SignerInfos:      emptySet,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs7ContentInfo{
+		ContentType: oidPKCS7SignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedData},
+	})
+}
+
+// WritePKCS7 writes the DER encoded certificates as a PKCS#7 bundle to fileName.
+func WritePKCS7(fileName string, certs ...[]byte) error {
+	data, err := EncodePKCS7(certs...)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, data, 0644)
+}
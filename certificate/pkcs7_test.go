@@ -0,0 +1,102 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+// readPKCS7 returns the certificates of a PKCS#7 bundle.
+func readPKCS7(t *testing.T, data []byte) []*x509.Certificate {
+	var info pkcs7ContentInfo
+	if rest, err := asn1.Unmarshal(data, &info); err != nil || len(rest) > 0 {
+		t.Fatalf("failed to parse content info: %v", err)
+	}
+	if !info.ContentType.Equal(oidPKCS7SignedData) {
+		t.Fatalf("got content type %v, want signed data", info.ContentType)
+	}
+	var signedData pkcs7SignedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &signedData); err != nil {
+		t.Fatalf("failed to parse signed data: %v", err)
+	}
+	if len(signedData.SignerInfos.Bytes) != 0 {
+		t.Fatal("bundle has signers")
+	}
+	certs, err := x509.ParseCertificates(signedData.Certificates.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse certificates: %v", err)
+	}
+	return certs
+}
+
+func TestWritePKCS7(t *testing.T) {
+	ca, caPriv := createCA()
+	caBytes := Sign(ca, ca, key.PublicKey(caPriv), caPriv)
+	client, clientPriv := createClient()
+	clientBytes := Sign(client, ca, key.PublicKey(clientPriv), caPriv)
+	fileName := filepath.Join(t.TempDir(), "chain.p7b")
+	if err := WritePKCS7(fileName, clientBytes, caBytes); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(fileName)
+	certs := readPKCS7(t, data)
+	if len(certs) != 2 || !bytes.Equal(certs[0].Raw, clientBytes) || !bytes.Equal(certs[1].Raw, caBytes) {
+		t.Fatalf("got %d certificates, want the client and the CA in order", len(certs))
+	}
+}
+
+// generated with openssl crl2pkcs7 -nocrl -certfile chain.pem, where chain.pem holds a
+// leaf for www.foo.se and its CA, openssl pkcs7 -print_certs lists:
+//
+//	subject=CN = www.foo.se
+//	issuer=CN = ca
+//
+//	subject=CN = ca
+//	issuer=CN = ca
+const opensslPKCS7 = `-----BEGIN PKCS7-----
+MIICvgYJKoZIhvcNAQcCoIICrzCCAqsCAQExADALBgkqhkiG9w0BBwGgggKTMIIB
+HDCBwwIUN4c6HDRpMl5yvItvGqs5ZQq6TpowCgYIKoZIzj0EAwIwDTELMAkGA1UE
+AwwCY2EwHhcNMjYxMDE2MDkzNTAxWhcNMzYxMDEzMDkzNTAxWjAVMRMwEQYDVQQD
+DAp3d3cuZm9vLnNlMFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEwHtwtJecock0
++64fX4sinjhp6pveAIOfKKHFhiceQOysecuHsRH03+4O8F4hekoJqHMacQd4bxRs
+EH+fZQbe/zAKBggqhkjOPQQDAgNIADBFAiB31S5cYuQG7+4WxX8+kJROGH+EU+D3
+GcOmjnZSTAElUgIhALbj96Dkv+Xf0+rz42CVf7J5pNx0MnnB+O7gRmVYL3wlMIIB
+bzCCARWgAwIBAgIUGQdiVSrkkM5blgKz/KY9EcHCwTkwCgYIKoZIzj0EAwIwDTEL
+MAkGA1UEAwwCY2EwHhcNMjYxMDE2MDkzNTAxWhcNMzYxMDEzMDkzNTAxWjANMQsw
+CQYDVQQDDAJjYTBZMBMGByqGSM49AgEGCCqGSM49AwEHA0IABDyKqaiWOcZqf+CB
+xDmFNWVFo6Yh0CLm7fKVsl8kfZ1EbUsBw9LI+Mslvc9PKsaU64r6OhwgZz89i9Pk
+vfad8iijUzBRMB0GA1UdDgQWBBS8Ao5F1QyjgnWFrOuhXrTvilR8hTAfBgNVHSME
+GDAWgBS8Ao5F1QyjgnWFrOuhXrTvilR8hTAPBgNVHRMBAf8EBTADAQH/MAoGCCqG
+SM49BAMCA0gAMEUCIQDnHMGijxhFBmrqEBnnZhW1+dU9UJnYx2KFwLGClvdCEgIg
+CgaJDs+5cu6nsdAT96myNgWPP8bTeQ4DDdxA1zqEuOwxAA==
+-----END PKCS7-----`
+
+func TestEncodePKCS7MatchesOpenssl(t *testing.T) {
+	block, _ := pem.Decode([]byte(opensslPKCS7))
+	certs := readPKCS7(t, block.Bytes)
+	if len(certs) != 2 || certs[0].Subject.CommonName != "www.foo.se" || certs[1].Subject.CommonName != "ca" {
+		t.Fatalf("got %d certificates, want www.foo.se and ca as openssl prints them", len(certs))
+	}
+	data, err := EncodePKCS7(certs[0].Raw, certs[1].Raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, block.Bytes) {
+		t.Fatalf("got %x, want the openssl bundle %x", data, block.Bytes)
+	}
+}
+
+func TestEncodePKCS7Invalid(t *testing.T) {
+	if _, err := EncodePKCS7(); err == nil {
+		t.Fatal("expected error without certificates")
+	}
+	if _, err := EncodePKCS7([]byte("garbage")); err == nil {
+		t.Fatal("expected error for an invalid certificate")
+	}
+}
@@ -0,0 +1,63 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+)
+
+// oidCTPoison marks a precertificate, see RFC 6962 section 3.1.
+var oidCTPoison = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 3}
+
+// Precertificate is a certificate transparency precertificate waiting for its SCTs.
+type Precertificate struct {
+	// DER is the signed precertificate to submit to the logs.
+	DER []byte
+
+	issuer   *Issuer
+	data     Certificate
+	serial   *big.Int
+	template *x509.Certificate
+}
+
+// IssuePrecertificate signs a precertificate for data, carrying the critical CT poison
+// extension. Final issues the certificate itself from the same template, so serial,
+// validity and names are the same in both.
+func (i *Issuer) IssuePrecertificate(data Certificate) (*Precertificate, error) {
+	if len(data.SCTList) > 0 {
+		return nil, errors.New("precertificate must not have an SCT list")
+	}
+	serial, err := i.nextSerial()
+	if err != nil {
+		return nil, err
+	}
+	template, err := i.prepare(&data, serial)
+	if err != nil {
+		return nil, err
+	}
+	precert := withExtension(template, pkix.Extension{Id: oidCTPoison, Critical: true, Value: asn1.NullBytes})
+	der, err := signIssued(precert, data, i.Cert, i.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &Precertificate{DER: der, issuer: i, data: data, serial: serial, template: template}, nil
+}
+
+// Final issues the certificate for the precertificate with the SCTs returned by the
+// logs. It differs from the precertificate only by the SCT list replacing the poison.
+func (p *Precertificate) Final(scts [][]byte) ([]byte, error) {
+	ext, err := sctListExtension(scts)
+	if err != nil {
+		return nil, err
+	}
+	return p.issuer.signAndStore(withExtension(p.template, ext), p.data, p.serial)
+}
+
+// withExtension returns a copy of template with ext added last.
+func withExtension(template *x509.Certificate, ext pkix.Extension) *x509.Certificate {
+	cert := *template
+	cert.ExtraExtensions = append(append([]pkix.Extension{}, template.ExtraExtensions...), ext)
+	return &cert
+}
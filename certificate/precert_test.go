@@ -0,0 +1,66 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"reflect"
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+// extensionsWithout returns the extensions of cert except for the one with id.
+func extensionsWithout(cert *x509.Certificate, id asn1.ObjectIdentifier) []string {
+	var exts []string
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(id) {
+			exts = append(exts, ext.Id.String()+":"+string(ext.Value))
+		}
+	}
+	return exts
+}
+
+func TestIssuePrecertificate(t *testing.T) {
+	issuer := testIssuer(t)
+	data := Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se", "foo.se"}, PrivateKey: key.GenerateKey("P256", 0)}
+	precert, err := issuer.IssuePrecertificate(data)
+	if err != nil {
+		t.Fatalf("failed to issue precertificate: %v", err)
+	}
+	pre := parseCert(t, precert.DER)
+	var poisoned bool
+	for _, ext := range pre.Extensions {
+		if ext.Id.Equal(oidCTPoison) {
+			poisoned = ext.Critical
+		}
+	}
+	if !poisoned {
+		t.Fatal("precertificate has no critical poison extension")
+	}
+	scts := [][]byte{{0x00, 0x01, 0x02}, {0x03}}
+	der, err := precert.Final(scts)
+	if err != nil {
+		t.Fatalf("failed to issue final certificate: %v", err)
+	}
+	final := parseCert(t, der)
+	if hasExtension(final, oidCTPoison) {
+		t.Fatal("final certificate has the poison extension")
+	}
+	if got, err := SignedCertificateTimestamps(final); err != nil || !reflect.DeepEqual(got, scts) {
+		t.Fatalf("got SCTs %x %v, want %x", got, err, scts)
+	}
+	if pre.SerialNumber.Cmp(final.SerialNumber) != 0 || !pre.NotBefore.Equal(final.NotBefore) || !pre.NotAfter.Equal(final.NotAfter) {
+		t.Fatalf("got serial %v and validity %v to %v, want %v and %v to %v", final.SerialNumber, final.NotBefore, final.NotAfter, pre.SerialNumber, pre.NotBefore, pre.NotAfter)
+	}
+	if !reflect.DeepEqual(extensionsWithout(pre, oidCTPoison), extensionsWithout(final, oidSCTList)) {
+		t.Fatal("extensions of the final certificate differ from the precertificate")
+	}
+}
+
+func TestIssuePrecertificateWithSCTs(t *testing.T) {
+	issuer := testIssuer(t)
+	data := Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: key.GenerateKey("P256", 0), SCTList: [][]byte{{0x01}}}
+	if _, err := issuer.IssuePrecertificate(data); err == nil {
+		t.Fatal("expected precertificate with SCTs to be rejected")
+	}
+}
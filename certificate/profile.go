@@ -0,0 +1,34 @@
+package certificate
+
+// CodeSigningCertificate returns a certificate for signing code, such as installers
+// and scripts, with the digital signature key usage and code signing as the only
+// extended key usage. It is valid for three years unless ValidFor is set.
+func CodeSigningCertificate(cn string, org string) Certificate {
+	return Certificate{
+		CommonName:   cn,
+		Organization: org,
+		Usage:        []string{"signature", "codesigning"},
+	}
+}
+
+// EmailCertificate returns an S/MIME certificate for signing and encrypting mail to
+// address, with the address as rfc822Name alternative name and email protection as
+// the only extended key usage.
+func EmailCertificate(address, name, org string) Certificate {
+	return Certificate{
+		CommonName:     name,
+		Organization:   org,
+		EmailAddresses: []string{address},
+		Usage:          []string{"signature", "encipherment", "emailprotection"},
+	}
+}
+
+// TSAProfile returns a certificate for an RFC 3161 timestamping authority, with
+// timestamping as the only extended key usage, marked critical as the RFC requires.
+func TSAProfile(cn string, org string) Certificate {
+	return Certificate{
+		CommonName:   cn,
+		Organization: org,
+		Usage:        []string{"signature", "contentcommitment", "timestamping"},
+	}
+}
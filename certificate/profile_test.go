@@ -0,0 +1,105 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestCodeSigningCertificate(t *testing.T) {
+	ca, err := NewCA(Certificate{Id: "ca", CommonName: "ca", CA: true, PrivateKey: key.GenerateKey("P256", 0), ValidFor: 10 * 365 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	issuer := NewIssuer(ca.Cert, ca.Key)
+	data := CodeSigningCertificate("Foo Installer Signing", "Foo AB")
+	data.PrivateKey = key.GenerateKey("P256", 0)
+	der, err := issuer.Issue(data)
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	cert := parseCert(t, der)
+	if cert.KeyUsage != x509.KeyUsageDigitalSignature {
+		t.Fatalf("got key usage %v, want digital signature", cert.KeyUsage)
+	}
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageCodeSigning {
+		t.Fatalf("got: %v, want only code signing", cert.ExtKeyUsage)
+	}
+	if validity := cert.NotAfter.Sub(cert.NotBefore); validity < 2*365*24*time.Hour {
+		t.Fatalf("got validity %v, want longer than the default", validity)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(issuer.Cert)
+	opts := x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}}
+	if _, err := cert.Verify(opts); err != nil {
+		t.Fatalf("failed to verify for code signing: %v", err)
+	}
+	opts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	if _, err := cert.Verify(opts); err == nil {
+		t.Fatal("code signing certificate verified for server authentication")
+	}
+}
+
+func TestEmailCertificate(t *testing.T) {
+	issuer := testIssuer(t)
+	data := EmailCertificate("anna@foo.se", "Anna Andersson", "Foo AB")
+	data.EmailInSubject = true
+	data.PrivateKey = key.GenerateKey("P256", 0)
+	der, err := issuer.Issue(data)
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	cert := parseCert(t, der)
+	if len(cert.EmailAddresses) != 1 || cert.EmailAddresses[0] != "anna@foo.se" {
+		t.Fatalf("got: %v, want the address as SAN", cert.EmailAddresses)
+	}
+	if cert.KeyUsage != x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment {
+		t.Fatalf("got key usage %v, want digital signature and key encipherment", cert.KeyUsage)
+	}
+	var inSubject bool
+	for _, name := range cert.Subject.Names {
+		if name.Type.Equal(oidEmailAddress) && name.Value == "anna@foo.se" {
+			inSubject = true
+		}
+	}
+	if !inSubject {
+		t.Fatalf("got subject %v, want the emailAddress attribute", cert.Subject)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(issuer.Cert)
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection}}); err != nil {
+		t.Fatalf("failed to verify for email protection: %v", err)
+	}
+	if FromX509(cert).EmailAddresses[0] != "anna@foo.se" {
+		t.Fatal("FromX509 lost the email address")
+	}
+	data.EmailAddresses = []string{"Anna <anna@foo.se>"}
+	if _, err := issuer.Issue(data); err == nil {
+		t.Fatal("expected error for an address with display name")
+	}
+}
+
+func TestTSAProfile(t *testing.T) {
+	issuer := testIssuer(t)
+	data := TSAProfile("Foo Timestamping", "Foo AB")
+	data.PrivateKey = key.GenerateKey("P256", 0)
+	der, err := issuer.Issue(data)
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	cert := parseCert(t, der)
+	var critical bool
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidExtKeyUsage) {
+			critical = ext.Critical
+		}
+	}
+	if !critical {
+		t.Fatal("extended key usage is missing or not critical")
+	}
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageTimeStamping {
+		t.Fatalf("got: %v, want only timestamping", cert.ExtKeyUsage)
+	}
+}
@@ -0,0 +1,101 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+var oidQCStatements = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 3}
+
+// QC statement ids from ETSI EN 319 412-5.
+var (
+	OIDQcCompliance      = asn1.ObjectIdentifier{0, 4, 0, 1862, 1, 1}
+	OIDQcRetentionPeriod = asn1.ObjectIdentifier{0, 4, 0, 1862, 1, 3}
+	OIDQcSSCD            = asn1.ObjectIdentifier{0, 4, 0, 1862, 1, 4}
+	OIDQcPDS             = asn1.ObjectIdentifier{0, 4, 0, 1862, 1, 5}
+	OIDQcType            = asn1.ObjectIdentifier{0, 4, 0, 1862, 1, 6}
+	// QcType values for qualified certificates for electronic signatures, seals and websites.
+	OIDQcTypeESign = asn1.ObjectIdentifier{0, 4, 0, 1862, 1, 6, 1}
+	OIDQcTypeESeal = asn1.ObjectIdentifier{0, 4, 0, 1862, 1, 6, 2}
+	OIDQcTypeWeb   = asn1.ObjectIdentifier{0, 4, 0, 1862, 1, 6, 3}
+)
+
+// QCStatement is a statement in the qcStatements extension of RFC 3739. Info is
+// marshalled with encoding/asn1 and left out if nil.
+type QCStatement struct {
+	ID   asn1.ObjectIdentifier
+	Info interface{}
+}
+
+// PDSLocation points to a PKI disclosure statement in the given two letter language.
+type PDSLocation struct {
+	URL      string `asn1:"ia5"`
+	Language string `asn1:"printable"`
+}
+
+// QcCompliance states that the certificate is an EU qualified certificate.
+func QcCompliance() QCStatement {
+	return QCStatement{ID: OIDQcCompliance}
+}
+
+// QcSSCD states that the private key is kept in a qualified signature creation device.
+func QcSSCD() QCStatement {
+	return QCStatement{ID: OIDQcSSCD}
+}
+
+// QcType states what the qualified certificate is for, e.g. OIDQcTypeWeb.
+func QcType(types ...asn1.ObjectIdentifier) QCStatement {
+	return QCStatement{ID: OIDQcType, Info: types}
+}
+
+// QcRetentionPeriod states for how many years after expiry the registration
+// information is kept.
+func QcRetentionPeriod(years int) QCStatement {
+	return QCStatement{ID: OIDQcRetentionPeriod, Info: years}
+}
+
+// QcPDS points to the PKI disclosure statements of the CA.
+func QcPDS(locations ...PDSLocation) QCStatement {
+	return QCStatement{ID: OIDQcPDS, Info: locations}
+}
+
+// QCStatements adds the qcStatements extension with the statements to the template.
+func QCStatements(cert *x509.Certificate, statements ...QCStatement) error {
+	ext, err := qcStatementsExtension(statements)
+	if err != nil {
+		return err
+	}
+	cert.ExtraExtensions = append(cert.ExtraExtensions, ext)
+	return nil
+}
+
+type qcStatement struct {
+	ID   asn1.ObjectIdentifier
+	Info asn1.RawValue `asn1:"optional"`
+}
+
+func qcStatementsExtension(statements []QCStatement) (pkix.Extension, error) {
+	if len(statements) == 0 {
+		return pkix.Extension{}, errors.New("no QC statements")
+	}
+	encoded := make([]qcStatement, 0, len(statements))
+	for _, s := range statements {
+		statement := qcStatement{ID: s.ID}
+		if s.Info != nil {
+			info, err := asn1.Marshal(s.Info)
+			if err != nil {
+				return pkix.Extension{}, fmt.Errorf("failed to encode QC statement %v: %v", s.ID, err)
+			}
+			statement.Info = asn1.RawValue{FullBytes: info}
+		}
+		encoded = append(encoded, statement)
+	}
+	value, err := asn1.Marshal(encoded)
+	if err != nil {
+		return pkix.Extension{}, err
+	}
+	return pkix.Extension{Id: oidQCStatements, Value: value}, nil
+}
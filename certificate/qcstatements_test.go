@@ -0,0 +1,59 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+// checked with openssl asn1parse -inform der -i
+const qcStatementsHex = "30523008060604008e4601013013060604008e4601063009060704008e46010603300b060604008e46010302010f3024060604008e460105301a3018161268747470733a2f2f666f6f2e73652f7064731302656e"
+
+func TestQCStatements(t *testing.T) {
+	ca, caPriv := createCA()
+	priv := key.GenerateKey("RSA", 1024)
+	data := Certificate{
+		Id:               "qc",
+		CommonName:       "www.foo.se",
+		AlternativeNames: []string{"www.foo.se"},
+		PrivateKey:       priv,
+		AllowWeakKey:     true,
+		QCStatements: []QCStatement{
+			QcCompliance(),
+			QcType(OIDQcTypeWeb),
+			QcRetentionPeriod(15),
+			QcPDS(PDSLocation{URL: "https://foo.se/pds", Language: "en"}),
+		},
+	}
+	template := mustCreateTemplate(t, data)
+	cert, _ := x509.ParseCertificate(Sign(template, ca, key.PublicKey(priv), caPriv))
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidQCStatements) {
+			if ext.Critical {
+				t.Fatal("qcStatements extension is critical")
+			}
+			if got := hex.EncodeToString(ext.Value); got != qcStatementsHex {
+				t.Fatalf("got: %s, want %s", got, qcStatementsHex)
+			}
+			return
+		}
+	}
+	t.Fatal("qcStatements extension missing")
+}
+
+func TestQCStatementsTemplate(t *testing.T) {
+	template := &x509.Certificate{}
+	if err := QCStatements(template, QcCompliance(), QcSSCD()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// SEQUENCE of the QcCompliance and QcSSCD statements without info
+	want := "30143008060604008e4601013008060604008e460104"
+	if len(template.ExtraExtensions) != 1 || hex.EncodeToString(template.ExtraExtensions[0].Value) != want {
+		t.Fatalf("got: %v, want one extension %s", template.ExtraExtensions, want)
+	}
+	if err := QCStatements(template); err == nil {
+		t.Fatal("expected error without statements")
+	}
+}
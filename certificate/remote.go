@@ -0,0 +1,61 @@
+package certificate
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrTimeout is returned then the remote server did not answer in time.
+	ErrTimeout = errors.New("timeout connecting to server")
+	// ErrConnectionRefused is returned then nothing listens on the remote address.
+	ErrConnectionRefused = errors.New("connection refused by server")
+)
+
+// FetchServerCertificates returns the certificates presented by a TLS server, the Go
+// version of `echo | openssl s_client -connect host:443`. The chain is not verified
+// so that invalid chains can be inspected as well. A timeout of 0 means no timeout.
+func FetchServerCertificates(hostPort string, timeout time.Duration) ([]*x509.Certificate, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return FetchRemoteChain(ctx, hostPort, "")
+}
+
+// FetchRemoteChain returns the certificate chain presented by a TLS server, leaf first.
+// serverName overrides the name sent with SNI, if empty the host part of addr is used.
+// The chain is not verified, use CheckCertificate to verify it against your own roots.
+func FetchRemoteChain(ctx context.Context, addr string, serverName string) ([]*x509.Certificate, error) {
+	dialer := &tls.Dialer{
+		Config: &tls.Config{
+			InsecureSkipVerify: true,
+			ServerName:         serverName,
+		},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, dialError(addr, err)
+	}
+	defer conn.Close()
+	return conn.(*tls.Conn).ConnectionState().PeerCertificates, nil
+}
+
+func dialError(hostPort string, err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %s: %v", ErrTimeout, hostPort, err)
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("%w: %s", ErrConnectionRefused, hostPort)
+	}
+	return fmt.Errorf("failed to fetch certificates from %s: %v", hostPort, err)
+}
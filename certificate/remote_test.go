@@ -0,0 +1,97 @@
+package certificate
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchServerCertificates(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	certs, err := FetchServerCertificates(strings.TrimPrefix(server.URL, "https://"), time.Second)
+	if err != nil {
+		t.Fatalf("failed to fetch certificates: %v", err)
+	}
+	if len(certs) == 0 || !certs[0].Equal(server.Certificate()) {
+		t.Fatal("fetched certificate does not match the server certificate")
+	}
+}
+
+func TestFetchServerCertificatesNoTimeout(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	certs, err := FetchServerCertificates(strings.TrimPrefix(server.URL, "https://"), 0)
+	if err != nil || len(certs) == 0 {
+		t.Fatalf("got: %v %v, want the server certificate without timeout", certs, err)
+	}
+}
+
+func TestFetchServerCertificatesRefused(t *testing.T) {
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := l.Addr().String()
+	l.Close()
+	_, err := FetchServerCertificates(addr, time.Second)
+	if !errors.Is(err, ErrConnectionRefused) {
+		t.Fatalf("got: %v, want %v", err, ErrConnectionRefused)
+	}
+}
+
+func TestFetchServerCertificatesTimeout(t *testing.T) {
+	// accepts connections but never answers the TLS handshake
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	_, err := FetchServerCertificates(l.Addr().String(), 100*time.Millisecond)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("got: %v, want %v", err, ErrTimeout)
+	}
+}
+
+func TestFetchRemoteChainServerName(t *testing.T) {
+	var serverName string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName = hello.ServerName
+			return nil, nil
+		},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	chain, err := FetchRemoteChain(ctx, server.Listener.Addr().String(), "www.foo.se")
+	if err != nil {
+		t.Fatalf("failed to fetch chain: %v", err)
+	}
+	if serverName != "www.foo.se" {
+		t.Fatalf("got SNI: %q, want www.foo.se", serverName)
+	}
+	if len(chain) == 0 || !chain[0].Equal(server.Certificate()) {
+		t.Fatal("first certificate in chain is not the server leaf")
+	}
+}
+
+func TestFetchRemoteChainCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := FetchRemoteChain(ctx, "127.0.0.1:1", ""); err == nil {
+		t.Fatal("expected error for cancelled context")
+	}
+}
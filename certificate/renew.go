@@ -0,0 +1,110 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"time"
+)
+
+// extensions handled by the x509 package then creating a certificate from a template
+var standardExtensions = []asn1.ObjectIdentifier{
+	{2, 5, 29, 14},              // subject key identifier
+	{2, 5, 29, 15},              // key usage
+	{2, 5, 29, 17},              // subject alternative name
+	{2, 5, 29, 19},              // basic constraints
+	{2, 5, 29, 30},              // name constraints
+	{2, 5, 29, 31},              // crl distribution points
+	{2, 5, 29, 32},              // certificate policies
+	{2, 5, 29, 35},              // authority key identifier
+	{2, 5, 29, 37},              // extended key usage
+	{1, 3, 6, 1, 5, 5, 7, 1, 1}, // authority information access
+}
+
+// certificate transparency extensions that belong to the old certificate only, its
+// SCTs do not cover the renewed one and a renewed certificate is no precertificate
+var renewDroppedExtensions = []asn1.ObjectIdentifier{oidSCTList, oidCTPoison}
+
+type renewConfig struct {
+	preserveExtensions bool
+}
+
+// RenewOption configures Renew.
+type RenewOption func(*renewConfig)
+
+// PreserveExtensions copies the issuer URLs, OCSP servers, CRL distribution points and
+// policy identifiers and the non-standard extensions from the old certificate to the
+// renewed one. The certificate transparency SCT list and precertificate poison are
+// never copied.
+func PreserveExtensions() RenewOption {
+	return func(c *renewConfig) {
+		c.preserveExtensions = true
+	}
+}
+
+// Renew issues a new certificate from an existing one. The subject, alternative names,
+// key usages, basic constraints and the public key are carried over unchanged, the
+// certificate gets a fresh serial and is valid for newValidity from now.
+func Renew(oldDER []byte, signerCert *x509.Certificate, signerKey interface{}, newValidity time.Duration, opts ...RenewOption) ([]byte, error) {
+	config := renewConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	old, err := x509.ParseCertificate(oldDER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate to renew: %v", err)
+	}
+	if newValidity <= 0 {
+		return nil, fmt.Errorf("validity must be positive, got: %v", newValidity)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	notBefore := time.Now().Add(-defaultNotBeforeSkew)
+	cert := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               old.Subject,
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(newValidity),
+		SubjectKeyId:          old.SubjectKeyId,
+		KeyUsage:              old.KeyUsage,
+		ExtKeyUsage:           old.ExtKeyUsage,
+		UnknownExtKeyUsage:    old.UnknownExtKeyUsage,
+		BasicConstraintsValid: old.BasicConstraintsValid,
+		IsCA:                  old.IsCA,
+		MaxPathLen:            old.MaxPathLen,
+		MaxPathLenZero:        old.MaxPathLenZero,
+		DNSNames:              old.DNSNames,
+		IPAddresses:           old.IPAddresses,
+		EmailAddresses:        old.EmailAddresses,
+		URIs:                  old.URIs,
+	}
+	// keep the subject exactly as encoded in the old certificate
+	cert.RawSubject = old.RawSubject
+	if config.preserveExtensions {
+		cert.IssuingCertificateURL = old.IssuingCertificateURL
+		cert.OCSPServer = old.OCSPServer
+		cert.CRLDistributionPoints = old.CRLDistributionPoints
+		cert.PolicyIdentifiers = old.PolicyIdentifiers
+		for _, ext := range old.Extensions {
+			if !isStandardExtension(ext.Id) && !oidInList(ext.Id, renewDroppedExtensions) {
+				cert.ExtraExtensions = append(cert.ExtraExtensions, ext)
+			}
+		}
+	}
+	return SignCertificate(cert, signerCert, old.PublicKey, signerKey)
+}
+
+func isStandardExtension(id asn1.ObjectIdentifier) bool {
+	return oidInList(id, standardExtensions)
+}
+
+func oidInList(id asn1.ObjectIdentifier, list []asn1.ObjectIdentifier) bool {
+	for _, oid := range list {
+		if oid.Equal(id) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestRenew(t *testing.T) {
+	ca, caPriv := createCA()
+	caBytes := Sign(ca, ca, key.PublicKey(caPriv), caPriv)
+	client, clientPriv := createClient()
+	clientBytes := Sign(client, ca, key.PublicKey(clientPriv), caPriv)
+
+	renewedBytes, err := Renew(clientBytes, ca, caPriv, 48*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to renew: %v", err)
+	}
+	old, _ := x509.ParseCertificate(clientBytes)
+	renewed, _ := x509.ParseCertificate(renewedBytes)
+	if !bytes.Equal(old.RawSubjectPublicKeyInfo, renewed.RawSubjectPublicKeyInfo) {
+		t.Fatal("renewed certificate has a different public key")
+	}
+	if old.SerialNumber.Cmp(renewed.SerialNumber) == 0 {
+		t.Fatal("renewed certificate has the same serial")
+	}
+	if renewed.NotAfter.Sub(renewed.NotBefore) != 48*time.Hour || renewed.NotAfter.Equal(old.NotAfter) {
+		t.Fatalf("wrong validity: %v - %v", renewed.NotBefore, renewed.NotAfter)
+	}
+	if !bytes.Equal(old.RawSubject, renewed.RawSubject) {
+		t.Fatalf("got subject: %v, want %v", renewed.Subject, old.Subject)
+	}
+	for _, name := range old.DNSNames {
+		if !CheckCertificate(name, caBytes, nil, renewedBytes) {
+			t.Fatalf("renewed certificate failed to verify for: %v", name)
+		}
+	}
+}
+
+func TestRenewPreserveExtensions(t *testing.T) {
+	ca, caPriv := createCA()
+	client, clientPriv := createClient()
+	custom := pkix.Extension{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}, Value: []byte{0x05, 0x00}}
+	client.ExtraExtensions = []pkix.Extension{custom}
+	clientBytes := Sign(client, ca, key.PublicKey(clientPriv), caPriv)
+
+	withoutBytes, _ := Renew(clientBytes, ca, caPriv, time.Hour)
+	withBytes, err := Renew(clientBytes, ca, caPriv, time.Hour, PreserveExtensions())
+	if err != nil {
+		t.Fatalf("failed to renew: %v", err)
+	}
+	without, _ := x509.ParseCertificate(withoutBytes)
+	with, _ := x509.ParseCertificate(withBytes)
+	if hasExtension(without, custom.Id) {
+		t.Fatal("custom extension copied without PreserveExtensions")
+	}
+	if !hasExtension(with, custom.Id) {
+		t.Fatal("custom extension not preserved")
+	}
+}
+
+func TestRenewPreservesStandardFields(t *testing.T) {
+	tests := map[string]struct {
+		set   func(*x509.Certificate)
+		equal func(old, renewed *x509.Certificate) bool
+	}{
+		"IssuingCertificateURL": {
+			func(c *x509.Certificate) { c.IssuingCertificateURL = []string{"http://ca.foo.se/ca.crt"} },
+			func(old, renewed *x509.Certificate) bool {
+				return reflect.DeepEqual(old.IssuingCertificateURL, renewed.IssuingCertificateURL)
+			},
+		},
+		"OCSPServer": {
+			func(c *x509.Certificate) { c.OCSPServer = []string{"http://ocsp.foo.se"} },
+			func(old, renewed *x509.Certificate) bool {
+				return reflect.DeepEqual(old.OCSPServer, renewed.OCSPServer)
+			},
+		},
+		"CRLDistributionPoints": {
+			func(c *x509.Certificate) { c.CRLDistributionPoints = []string{"http://ca.foo.se/ca.crl"} },
+			func(old, renewed *x509.Certificate) bool {
+				return reflect.DeepEqual(old.CRLDistributionPoints, renewed.CRLDistributionPoints)
+			},
+		},
+		"PolicyIdentifiers": {
+			func(c *x509.Certificate) { c.PolicyIdentifiers = []asn1.ObjectIdentifier{{2, 23, 140, 1, 2, 1}} },
+			func(old, renewed *x509.Certificate) bool {
+				return len(renewed.PolicyIdentifiers) == 1 && renewed.PolicyIdentifiers[0].Equal(old.PolicyIdentifiers[0])
+			},
+		},
+	}
+	ca, caPriv := createCA()
+	for name, tt := range tests {
+		client, clientPriv := createClient()
+		tt.set(client)
+		old, _ := x509.ParseCertificate(Sign(client, ca, key.PublicKey(clientPriv), caPriv))
+		renewedBytes, err := Renew(old.Raw, ca, caPriv, time.Hour, PreserveExtensions())
+		if err != nil {
+			t.Fatalf("%s: failed to renew: %v", name, err)
+		}
+		renewed, _ := x509.ParseCertificate(renewedBytes)
+		if !tt.equal(old, renewed) {
+			t.Fatalf("%s not preserved", name)
+		}
+	}
+}
+
+func TestRenewDropsCTExtensions(t *testing.T) {
+	ca, caPriv := createCA()
+	client, clientPriv := createClient()
+	client.ExtraExtensions = []pkix.Extension{
+		{Id: oidSCTList, Value: []byte{0x04, 0x02, 0x00, 0x00}},
+		{Id: oidCTPoison, Critical: true, Value: []byte{0x05, 0x00}},
+	}
+	clientBytes := Sign(client, ca, key.PublicKey(clientPriv), caPriv)
+	renewedBytes, err := Renew(clientBytes, ca, caPriv, time.Hour, PreserveExtensions())
+	if err != nil {
+		t.Fatalf("failed to renew: %v", err)
+	}
+	renewed, _ := x509.ParseCertificate(renewedBytes)
+	if hasExtension(renewed, oidSCTList) || hasExtension(renewed, oidCTPoison) {
+		t.Fatal("certificate transparency extension copied to the renewed certificate")
+	}
+}
+
+func TestRenewInvalidInput(t *testing.T) {
+	ca, caPriv := createCA()
+	if _, err := Renew([]byte("garbage"), ca, caPriv, time.Hour); err == nil {
+		t.Fatal("expected error for invalid certificate")
+	}
+}
+
+func hasExtension(cert *x509.Certificate, id asn1.ObjectIdentifier) bool {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(id) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var oidSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+
+// sctListExtension encodes the timestamps as the TLS SignedCertificateTimestampList
+// from RFC 6962 wrapped in the certificate transparency extension.
+func sctListExtension(scts [][]byte) (pkix.Extension, error) {
+	var list []byte
+	for i, sct := range scts {
+		if len(sct) == 0 {
+			return pkix.Extension{}, fmt.Errorf("SCT %d is empty", i)
+		}
+		if len(sct) > 0xffff {
+			return pkix.Extension{}, fmt.Errorf("SCT %d is too large: %d bytes", i, len(sct))
+		}
+		list = binary.BigEndian.AppendUint16(list, uint16(len(sct)))
+		list = append(list, sct...)
+	}
+	if len(list) > 0xffff {
+		return pkix.Extension{}, errors.New("SCT list is too large")
+	}
+	tlsList := binary.BigEndian.AppendUint16(nil, uint16(len(list)))
+	value, err := asn1.Marshal(append(tlsList, list...))
+	if err != nil {
+		return pkix.Extension{}, err
+	}
+	return pkix.Extension{Id: oidSCTList, Value: value}, nil
+}
+
+// SignedCertificateTimestamps returns the serialized SCTs embedded in cert, or nil if
+// it has no SCT list extension. crypto/x509 leaves the extension unparsed.
+func SignedCertificateTimestamps(cert *x509.Certificate) ([][]byte, error) {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidSCTList) {
+			continue
+		}
+		var tlsList []byte
+		if rest, err := asn1.Unmarshal(ext.Value, &tlsList); err != nil || len(rest) > 0 {
+			return nil, errors.New("invalid SCT list extension")
+		}
+		if len(tlsList) < 2 || int(binary.BigEndian.Uint16(tlsList)) != len(tlsList)-2 {
+			return nil, errors.New("invalid SCT list length")
+		}
+		var scts [][]byte
+		for list := tlsList[2:]; len(list) > 0; {
+			if len(list) < 2 {
+				return nil, errors.New("truncated SCT list")
+			}
+			n := int(binary.BigEndian.Uint16(list))
+			if n == 0 || len(list) < 2+n {
+				return nil, fmt.Errorf("invalid SCT %d in list", len(scts))
+			}
+			scts = append(scts, list[2:2+n])
+			list = list[2+n:]
+		}
+		return scts, nil
+	}
+	return nil, nil
+}
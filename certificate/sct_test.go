@@ -0,0 +1,63 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestSCTListExtension(t *testing.T) {
+	ca, caPriv := createCA()
+	priv := key.GenerateKey("RSA", 1024)
+	data := Certificate{
+		Id:           "four",
+		CommonName:   "www.foo.se",
+		PrivateKey:   priv,
+		AllowWeakKey: true,
+		SCTList:      [][]byte{{0x00, 0x01, 0x02}, {0x03}},
+	}
+	template := mustCreateTemplate(t, data)
+	cert, _ := x509.ParseCertificate(Sign(template, ca, key.PublicKey(priv), caPriv))
+	// OCTET STRING, list length 8, SCT of length 3 and SCT of length 1
+	want := []byte{0x04, 0x0a, 0x00, 0x08, 0x00, 0x03, 0x00, 0x01, 0x02, 0x00, 0x01, 0x03}
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidSCTList) {
+			if !bytes.Equal(ext.Value, want) {
+				t.Fatalf("got: %x, want %x", ext.Value, want)
+			}
+			return
+		}
+	}
+	t.Fatal("SCT list extension missing")
+}
+
+func TestSCTListEmptySCT(t *testing.T) {
+	data := Certificate{
+		Id:         "four",
+		PrivateKey: key.GenerateKey("P256", 0),
+		SCTList:    [][]byte{{0x01}, {}},
+	}
+	if _, err := NewCertificateTemplate(data); err == nil {
+		t.Fatal("expected error for empty SCT")
+	}
+}
+
+func TestSignedCertificateTimestamps(t *testing.T) {
+	ca, caPriv := createCA()
+	priv := key.GenerateKey("RSA", 1024)
+	scts := [][]byte{{0x00, 0x01, 0x02}, {0x03}}
+	template := mustCreateTemplate(t, Certificate{Id: "four", CommonName: "www.foo.se", PrivateKey: priv, AllowWeakKey: true, SCTList: scts})
+	cert, _ := x509.ParseCertificate(Sign(template, ca, key.PublicKey(priv), caPriv))
+	got, err := SignedCertificateTimestamps(cert)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || !bytes.Equal(got[0], scts[0]) || !bytes.Equal(got[1], scts[1]) {
+		t.Fatalf("got: %x, want %x", got, scts)
+	}
+	if got, err := SignedCertificateTimestamps(ca); got != nil || err != nil {
+		t.Fatalf("got: %x %v, want no SCTs", got, err)
+	}
+}
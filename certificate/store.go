@@ -0,0 +1,193 @@
+package certificate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by an IssuanceStore when no certificate has the serial.
+var ErrNotFound = errors.New("certificate not found")
+
+// IssueMeta is stored together with every issued certificate.
+type IssueMeta struct {
+	CommonName string
+	IssuedAt   time.Time
+	NotBefore  time.Time
+	NotAfter   time.Time
+	Revoked    bool
+	RevokedAt  time.Time
+	// RevocationReason is put on the CRL entry, ReasonCertificateHold for a certificate
+	// on hold and ReasonRemoveFromCRL for one released from hold in a delta CRL.
+	RevocationReason RevocationReason
+}
+
+// IssuedCertificate is a certificate kept in an IssuanceStore.
+type IssuedCertificate struct {
+	Serial *big.Int
+	DER    []byte
+	Meta   IssueMeta
+}
+
+// IssuanceStore remembers the certificates an Issuer has issued so serials are not
+// reused after a restart and revoked certificates can be put on a CRL.
+type IssuanceStore interface {
+	Put(serial *big.Int, der []byte, meta IssueMeta) error
+	Get(serial *big.Int) (IssuedCertificate, error)
+	List() ([]IssuedCertificate, error)
+	// MarkRevoked records the revocation of the certificate with serial. Marking an
+	// already revoked certificate replaces the time and reason, e.g. to revoke a
+	// certificate on hold for good or to release it with ReasonRemoveFromCRL.
+	MarkRevoked(serial *big.Int, at time.Time, reason RevocationReason) error
+}
+
+// RevokedEntries returns the revoked certificates in the store, ready to be used with CreateCRL.
+func RevokedEntries(store IssuanceStore) ([]RevokedEntry, error) {
+	issued, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	var revoked []RevokedEntry
+	for _, c := range issued {
+		if c.Meta.Revoked {
+			revoked = append(revoked, RevokedEntry{SerialNumber: c.Serial, RevocationTime: c.Meta.RevokedAt, Reason: c.Meta.RevocationReason})
+		}
+	}
+	return revoked, nil
+}
+
+// FileStore is an IssuanceStore saved as JSON in a single file. The whole file is
+// rewritten on every change, which is fine for the number of certificates a test or
+// development CA issues.
+type FileStore struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]IssuedCertificate
+}
+
+type fileStoreEntry struct {
+	Serial string
+	DER    []byte
+	Meta   IssueMeta
+}
+
+// NewFileStore opens the store in path, the file is created on the first Put.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, entries: map[string]IssuedCertificate{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store %s: %v", path, err)
+	}
+	var stored []fileStoreEntry
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse store %s: %v", path, err)
+	}
+	for _, e := range stored {
+		serial, ok := new(big.Int).SetString(e.Serial, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid serial %q in store %s", e.Serial, path)
+		}
+		s.entries[e.Serial] = IssuedCertificate{Serial: serial, DER: e.DER, Meta: e.Meta}
+	}
+	return s, nil
+}
+
+func (s *FileStore) Put(serial *big.Int, der []byte, meta IssueMeta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[serial.String()]; ok {
+		return fmt.Errorf("serial %v already stored", serial)
+	}
+	s.entries[serial.String()] = IssuedCertificate{Serial: new(big.Int).Set(serial), DER: der, Meta: meta}
+	if err := s.save(); err != nil {
+		delete(s.entries, serial.String())
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) Get(serial *big.Int) (IssuedCertificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.entries[serial.String()]
+	if !ok {
+		return IssuedCertificate{}, ErrNotFound
+	}
+	return c, nil
+}
+
+// List returns the stored certificates ordered by serial.
+func (s *FileStore) List() ([]IssuedCertificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sorted(), nil
+}
+
+func (s *FileStore) MarkRevoked(serial *big.Int, at time.Time, reason RevocationReason) error {
+	if _, ok := reasonNames[reason]; !ok {
+		return fmt.Errorf("unknown revocation reason %d", int(reason))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.entries[serial.String()]
+	if !ok {
+		return ErrNotFound
+	}
+	previous := c
+	c.Meta.Revoked = true
+	c.Meta.RevokedAt = at
+	c.Meta.RevocationReason = reason
+	s.entries[serial.String()] = c
+	if err := s.save(); err != nil {
+		s.entries[serial.String()] = previous
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) sorted() []IssuedCertificate {
+	list := make([]IssuedCertificate, 0, len(s.entries))
+	for _, c := range s.entries {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Serial.Cmp(list[j].Serial) < 0 })
+	return list
+}
+
+// save writes the store to a temporary file and renames it so a failed write
+// never leaves a half written store behind.
+func (s *FileStore) save() error {
+	var stored []fileStoreEntry
+	for _, c := range s.sorted() {
+		stored = append(stored, fileStoreEntry{Serial: c.Serial.String(), DER: c.DER, Meta: c.Meta})
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save store: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save store: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save store: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save store: %v", err)
+	}
+	return nil
+}
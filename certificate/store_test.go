@@ -0,0 +1,157 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+type failingStore struct {
+	FileStore
+}
+
+func (s *failingStore) Put(serial *big.Int, der []byte, meta IssueMeta) error {
+	return errors.New("disk full")
+}
+
+func TestFileStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "issued.json")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	if err := store.Put(big.NewInt(2), []byte{2}, IssueMeta{CommonName: "two"}); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	if err := store.Put(big.NewInt(1), []byte{1}, IssueMeta{CommonName: "one"}); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	if err := store.Put(big.NewInt(1), []byte{1}, IssueMeta{}); err == nil {
+		t.Fatal("expected error for duplicate serial")
+	}
+	revokedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := store.MarkRevoked(big.NewInt(2), revokedAt, ReasonKeyCompromise); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	if err := store.MarkRevoked(big.NewInt(3), revokedAt, ReasonKeyCompromise); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got: %v, want ErrNotFound", err)
+	}
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	list, _ := reopened.List()
+	if len(list) != 2 || list[0].Meta.CommonName != "one" || list[1].Meta.CommonName != "two" {
+		t.Fatalf("unexpected content after reload: %+v", list)
+	}
+	c, err := reopened.Get(big.NewInt(2))
+	if err != nil || !c.Meta.Revoked || !c.Meta.RevokedAt.Equal(revokedAt) || c.Meta.RevocationReason != ReasonKeyCompromise {
+		t.Fatalf("got: %+v %v, want revoked entry", c, err)
+	}
+	if _, err := reopened.Get(big.NewInt(3)); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got: %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileStoreRevocationReason(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "issued.json")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	for serial := int64(1); serial <= 3; serial++ {
+		if err := store.Put(big.NewInt(serial), []byte{byte(serial)}, IssueMeta{}); err != nil {
+			t.Fatalf("failed to put: %v", err)
+		}
+	}
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	store.MarkRevoked(big.NewInt(1), at, ReasonCertificateHold)
+	store.MarkRevoked(big.NewInt(2), at, ReasonCertificateHold)
+	// serial 2 is released from hold
+	if err := store.MarkRevoked(big.NewInt(2), at.Add(time.Hour), ReasonRemoveFromCRL); err != nil {
+		t.Fatalf("failed to release from hold: %v", err)
+	}
+	if err := store.MarkRevoked(big.NewInt(3), at, RevocationReason(7)); err == nil {
+		t.Fatal("expected error for an unknown revocation reason")
+	}
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	revoked, err := RevokedEntries(reopened)
+	if err != nil || len(revoked) != 2 {
+		t.Fatalf("got: %+v %v, want two entries", revoked, err)
+	}
+	if revoked[0].Reason != ReasonCertificateHold || revoked[1].Reason != ReasonRemoveFromCRL || !revoked[1].RevocationTime.Equal(at.Add(time.Hour)) {
+		t.Fatalf("got: %+v, want serial 1 on hold and serial 2 removed from the CRL", revoked)
+	}
+}
+
+func TestIssuerStoresAndRevokes(t *testing.T) {
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "issued.json"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	issuer := testIssuer(t)
+	issuer.Store = store
+	der, err := issuer.Issue(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: key.GenerateKey("P256", 0)})
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	stored, err := store.Get(cert.SerialNumber)
+	if err != nil || stored.Meta.CommonName != "www.foo.se" {
+		t.Fatalf("got: %+v %v, want stored certificate", stored, err)
+	}
+	if err := store.MarkRevoked(cert.SerialNumber, time.Now(), ReasonUnspecified); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	revoked, err := RevokedEntries(store)
+	if err != nil || len(revoked) != 1 || revoked[0].SerialNumber.Cmp(cert.SerialNumber) != 0 {
+		t.Fatalf("got: %+v %v, want the issued serial", revoked, err)
+	}
+	crlBytes, err := CreateCRL(issuer.Cert, issuer.Key, revoked, big.NewInt(1), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create CRL: %v", err)
+	}
+	caBytes := issuer.Cert.Raw
+	err = VerifyWithCRL("www.foo.se", caBytes, nil, der, crlBytes)
+	var revokedErr *RevokedError
+	if !errors.As(err, &revokedErr) || revokedErr.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+		t.Fatalf("got: %v, want a revoked error for serial %v", err, cert.SerialNumber)
+	}
+}
+
+func TestIssuerFailsWhenStoreFails(t *testing.T) {
+	issuer := testIssuer(t)
+	issuer.Store = &failingStore{FileStore{entries: map[string]IssuedCertificate{}}}
+	if _, err := issuer.Issue(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: key.GenerateKey("P256", 0)}); err == nil {
+		t.Fatal("expected issuance to fail when the store fails")
+	}
+}
+
+func TestIssuerSkipsStoredSerials(t *testing.T) {
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "issued.json"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	store.Put(big.NewInt(1), []byte{1}, IssueMeta{})
+	issuer := testIssuer(t)
+	issuer.Serials = &MonotonicSerials{}
+	issuer.Store = store
+	der, err := issuer.Issue(Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, PrivateKey: key.GenerateKey("P256", 0)})
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	if cert.SerialNumber.Int64() != 2 {
+		t.Fatalf("got serial %v, want 2", cert.SerialNumber)
+	}
+}
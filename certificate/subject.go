@@ -0,0 +1,68 @@
+package certificate
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SubjectAttribute is an extra attribute of the subject, such as a private
+// 1.3.6.1.4.1.99999.2.1 factoryCode. The value is stored as a PrintableString if
+// it fits, otherwise as a UTF8String.
+type SubjectAttribute struct {
+	OID   string
+	Value string
+}
+
+var (
+	oidCommonName         = asn1.ObjectIdentifier{2, 5, 4, 3}
+	oidCountry            = asn1.ObjectIdentifier{2, 5, 4, 6}
+	oidOrganization       = asn1.ObjectIdentifier{2, 5, 4, 10}
+	oidOrganizationalUnit = asn1.ObjectIdentifier{2, 5, 4, 11}
+)
+
+// parseOID parses an object identifier in dotted form, such as 1.3.6.1.4.1.99999.
+func parseOID(s string) (asn1.ObjectIdentifier, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID %q: at least two arcs are required", s)
+	}
+	oid := make(asn1.ObjectIdentifier, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || strings.TrimLeft(part, "0123456789") != "" || (len(part) > 1 && part[0] == '0') {
+			return nil, fmt.Errorf("invalid OID %q: arc %q is not a number", s, part)
+		}
+		oid[i] = n
+	}
+	if oid[0] > 2 || (oid[0] < 2 && oid[1] > 39) {
+		return nil, fmt.Errorf("invalid OID %q: first arcs out of range", s)
+	}
+	return oid, nil
+}
+
+// subjectAttributes converts ExtraSubjectAttributes to names, rejecting attributes
+// that duplicate the ones set by the other fields of data.
+func subjectAttributes(data Certificate) ([]pkix.AttributeTypeAndValue, error) {
+	taken := map[string]bool{
+		oidCommonName.String():         data.CommonName != "",
+		oidCountry.String():            data.Country != "",
+		oidOrganization.String():       data.Organization != "",
+		oidOrganizationalUnit.String(): data.OrganizationalUnit != "",
+		oidEmailAddress.String():       data.EmailInSubject && len(data.EmailAddresses) > 0,
+	}
+	var names []pkix.AttributeTypeAndValue
+	for _, attr := range data.ExtraSubjectAttributes {
+		oid, err := parseOID(attr.OID)
+		if err != nil {
+			return nil, err
+		}
+		if taken[oid.String()] {
+			return nil, fmt.Errorf("subject attribute %s is already set by another field", oid)
+		}
+		names = append(names, pkix.AttributeTypeAndValue{Type: oid, Value: attr.Value})
+	}
+	return names, nil
+}
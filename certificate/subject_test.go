@@ -0,0 +1,63 @@
+package certificate
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestExtraSubjectAttributes(t *testing.T) {
+	ca, caPriv := createCA()
+	priv := key.GenerateKey("RSA", 1024)
+	template := mustCreateTemplate(t, Certificate{
+		Id:           "device",
+		CommonName:   "device-17",
+		PrivateKey:   priv,
+		AllowWeakKey: true,
+		Usage:        []string{"signature", "clientauth"},
+		ExtraSubjectAttributes: []SubjectAttribute{
+			{OID: "1.3.6.1.4.1.99999.2.1", Value: "F-17"},
+			{OID: "1.3.6.1.4.1.99999.2.2", Value: "Fabrik Å"},
+		},
+	})
+	cert := parseCert(t, Sign(template, ca, key.PublicKey(priv), caPriv))
+	want := map[string]string{"1.3.6.1.4.1.99999.2.1": "F-17", "1.3.6.1.4.1.99999.2.2": "Fabrik Å"}
+	for _, name := range cert.Subject.Names {
+		if v, ok := want[name.Type.String()]; ok && name.Value == v {
+			delete(want, name.Type.String())
+		}
+	}
+	if len(want) > 0 {
+		t.Fatalf("got: %v, missing %v", cert.Subject.Names, want)
+	}
+	// PrintableString for F-17 and UTF8String for the value with Å
+	for tag, value := range map[int]string{asn1.TagPrintableString: "F-17", asn1.TagUTF8String: "Fabrik Å"} {
+		encoded, _ := asn1.Marshal(asn1.RawValue{Tag: tag, Bytes: []byte(value)})
+		if !bytes.Contains(cert.RawSubject, encoded) {
+			t.Fatalf("%s is not encoded with tag %d", value, tag)
+		}
+	}
+}
+
+func TestExtraSubjectAttributesInvalid(t *testing.T) {
+	tests := map[string]Certificate{
+		"bad oid":         {ExtraSubjectAttributes: []SubjectAttribute{{OID: "1.3.six", Value: "x"}}},
+		"single arc":      {ExtraSubjectAttributes: []SubjectAttribute{{OID: "1", Value: "x"}}},
+		"first arc":       {ExtraSubjectAttributes: []SubjectAttribute{{OID: "3.1", Value: "x"}}},
+		"duplicate cn":    {CommonName: "www.foo.se", ExtraSubjectAttributes: []SubjectAttribute{{OID: "2.5.4.3", Value: "x"}}},
+		"duplicate email": {EmailAddresses: []string{"anna@foo.se"}, EmailInSubject: true, ExtraSubjectAttributes: []SubjectAttribute{{OID: "1.2.840.113549.1.9.1", Value: "x"}}},
+	}
+	for name, data := range tests {
+		data.Id = "device"
+		data.PrivateKey = key.GenerateKey("P256", 0)
+		if _, err := NewCertificateTemplate(data); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	data := Certificate{Id: "device", PrivateKey: key.GenerateKey("P256", 0), ExtraSubjectAttributes: []SubjectAttribute{{OID: "2.5.4.3", Value: "device-17"}}}
+	if _, err := NewCertificateTemplate(data); err != nil {
+		t.Errorf("unexpected error for a common name only set as attribute: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"time"
+)
+
+var oidSubjectDirectoryAttributes = asn1.ObjectIdentifier{2, 5, 29, 9}
+
+// Personal data attributes from RFC 3739 section 3.2.2.
+var (
+	OIDDateOfBirth  = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 9, 1}
+	OIDPlaceOfBirth = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 9, 2}
+)
+
+// DirectoryAttribute is an attribute in the subjectDirectoryAttributes extension,
+// Value is the DER encoded attribute value.
+type DirectoryAttribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+// DateOfBirth is the date of birth, encoded as a GeneralizedTime at noon GMT as RFC
+// 3739 recommends so that the date is the same in every time zone.
+func DateOfBirth(date time.Time) DirectoryAttribute {
+	noon := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, time.UTC)
+	return DirectoryAttribute{Type: OIDDateOfBirth, Value: asn1.RawValue{Tag: asn1.TagGeneralizedTime, Bytes: []byte(noon.Format("20060102150405Z"))}}
+}
+
+// PlaceOfBirth is the place of birth, encoded as a UTF8String.
+func PlaceOfBirth(place string) DirectoryAttribute {
+	return DirectoryAttribute{Type: OIDPlaceOfBirth, Value: asn1.RawValue{Tag: asn1.TagUTF8String, Bytes: []byte(place)}}
+}
+
+// SubjectDirectoryAttributes adds the non-critical subjectDirectoryAttributes
+// extension with the attributes to the template.
+func SubjectDirectoryAttributes(cert *x509.Certificate, attributes ...DirectoryAttribute) error {
+	ext, err := subjectDirectoryAttributesExtension(attributes)
+	if err != nil {
+		return err
+	}
+	cert.ExtraExtensions = append(cert.ExtraExtensions, ext)
+	return nil
+}
+
+type directoryAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+func subjectDirectoryAttributesExtension(attributes []DirectoryAttribute) (pkix.Extension, error) {
+	if len(attributes) == 0 {
+		return pkix.Extension{}, errors.New("no subject directory attributes")
+	}
+	encoded := make([]directoryAttribute, 0, len(attributes))
+	for _, a := range attributes {
+		encoded = append(encoded, directoryAttribute{Type: a.Type, Values: []asn1.RawValue{a.Value}})
+	}
+	value, err := asn1.Marshal(encoded)
+	if err != nil {
+		return pkix.Extension{}, err
+	}
+	// RFC 5280 section 4.2.1.8, conforming CAs must mark this extension as non-critical
+	return pkix.Extension{Id: oidSubjectDirectoryAttributes, Value: value}, nil
+}
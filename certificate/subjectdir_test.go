@@ -0,0 +1,57 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+// generated with openssl asn1parse -genconf
+const subjectDirectoryAttributesHex = "3038301d06082b060105050709013111180f31393830303531373132303030305a301706082b06010505070902310b0c0953746f636b686f6c6d"
+
+func TestSubjectDirectoryAttributes(t *testing.T) {
+	ca, caPriv := createCA()
+	priv := key.GenerateKey("RSA", 1024)
+	// just after midnight, the date must not move to the previous day in GMT
+	born := time.Date(1980, time.May, 17, 0, 30, 0, 0, time.FixedZone("CET", 3600))
+	data := Certificate{
+		Id:                  "id",
+		CommonName:          "Anna Andersson",
+		PrivateKey:          priv,
+		AllowWeakKey:        true,
+		Usage:               []string{"signature", "clientauth"},
+		DirectoryAttributes: []DirectoryAttribute{DateOfBirth(born), PlaceOfBirth("Stockholm")},
+	}
+	cert, _ := x509.ParseCertificate(Sign(mustCreateTemplate(t, data), ca, key.PublicKey(priv), caPriv))
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidSubjectDirectoryAttributes) {
+			if ext.Critical {
+				t.Fatal("subjectDirectoryAttributes extension is critical")
+			}
+			if got := hex.EncodeToString(ext.Value); got != subjectDirectoryAttributesHex {
+				t.Fatalf("got: %s, want %s", got, subjectDirectoryAttributesHex)
+			}
+			return
+		}
+	}
+	t.Fatal("subjectDirectoryAttributes extension missing")
+}
+
+func TestSubjectDirectoryAttributesTemplate(t *testing.T) {
+	template := &x509.Certificate{}
+	if err := SubjectDirectoryAttributes(template); err == nil {
+		t.Fatal("expected error without attributes")
+	}
+	if err := SubjectDirectoryAttributes(template, PlaceOfBirth("Stockholm")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(template.ExtraExtensions) != 1 || !template.ExtraExtensions[0].Id.Equal(oidSubjectDirectoryAttributes) || template.ExtraExtensions[0].Critical {
+		t.Fatalf("got: %v, want one non-critical subjectDirectoryAttributes extension", template.ExtraExtensions)
+	}
+	if _, err := NewCertificateTemplate(Certificate{Id: "id", Version: 1, DirectoryAttributes: []DirectoryAttribute{PlaceOfBirth("Stockholm")}, PrivateKey: key.GenerateKey("P256", 0)}); err == nil {
+		t.Fatal("expected error for directory attributes in a version 1 certificate")
+	}
+}
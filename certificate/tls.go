@@ -0,0 +1,86 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// TLSCertificate creates a tls.Certificate from a signed leaf, its intermediates and the
+// private key of the leaf, ready to be used in a tls.Config. An error is returned if the
+// private key does not belong to the leaf certificate.
+func TLSCertificate(leafDER []byte, intermediates [][]byte, priv interface{}) (tls.Certificate, error) {
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to parse leaf certificate: %v", err)
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return tls.Certificate{}, fmt.Errorf("unsupported private key type: %T", priv)
+	}
+	if !publicKeysEqual(leaf.PublicKey, signer.Public()) {
+		return tls.Certificate{}, errors.New("private key does not match the leaf certificate")
+	}
+	chain := [][]byte{leafDER}
+	for i, der := range intermediates {
+		if _, err := x509.ParseCertificate(der); err != nil {
+			return tls.Certificate{}, fmt.Errorf("failed to parse intermediate %d: %v", i, err)
+		}
+		chain = append(chain, der)
+	}
+	return tls.Certificate{
+		Certificate: chain,
+		PrivateKey:  priv,
+		Leaf:        leaf,
+	}, nil
+}
+
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	k, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(b)
+}
+
+// ServerTLSConfig returns a tls.Config for a server presenting serverCert. Client
+// certificates are verified against the CA certificates in caPEM, requireClientCert
+// makes a valid client certificate mandatory.
+func ServerTLSConfig(caPEM []byte, serverCert tls.Certificate, requireClientCert bool) (*tls.Config, error) {
+	pool, err := certPoolFromPem(caPEM)
+	if err != nil {
+		return nil, err
+	}
+	clientAuth := tls.VerifyClientCertIfGiven
+	if requireClientCert {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pool,
+		ClientAuth:   clientAuth,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// ClientTLSConfig returns a tls.Config for a client presenting clientCert and
+// trusting only the CA certificates in caPEM for a server named serverName.
+func ClientTLSConfig(caPEM []byte, clientCert tls.Certificate, serverName string) (*tls.Config, error) {
+	pool, err := certPoolFromPem(caPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{clientCert},
+		RootCAs:      pool,
+		ServerName:   serverName,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+func certPoolFromPem(caPEM []byte) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("no CA certificates found in PEM data")
+	}
+	return pool, nil
+}
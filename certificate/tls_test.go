@@ -0,0 +1,111 @@
+package certificate
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestTLSCertificate(t *testing.T) {
+	ca, caPriv := createCA()
+	caBytes := Sign(ca, ca, key.PublicKey(caPriv), caPriv)
+	interCa, interCaPriv := createInterCA()
+	interCaBytes := Sign(interCa, ca, key.PublicKey(interCaPriv), caPriv)
+	client, clientPriv := createClient()
+	clientBytes := Sign(client, interCa, key.PublicKey(clientPriv), interCaPriv)
+
+	cert, err := TLSCertificate(clientBytes, [][]byte{interCaBytes}, clientPriv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cert.Certificate) != 2 || cert.Leaf == nil {
+		t.Fatalf("got chain of %d certificates, want 2", len(cert.Certificate))
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	caCert, _ := x509.ParseCertificate(caBytes)
+	roots.AddCert(caCert)
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "www.baz.se"}}}
+	resp, err := httpClient.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestTLSCertificateKeyMismatch(t *testing.T) {
+	ca, caPriv := createCA()
+	client, clientPriv := createClient()
+	clientBytes := Sign(client, ca, key.PublicKey(clientPriv), caPriv)
+	if _, err := TLSCertificate(clientBytes, nil, caPriv); err == nil {
+		t.Fatal("expected error for mismatching private key")
+	}
+}
+
+func TestMutualTLSConfig(t *testing.T) {
+	ca, caPriv := createCA()
+	caBytes := Sign(ca, ca, key.PublicKey(caPriv), caPriv)
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caBytes})
+
+	serverPriv := key.GenerateKey("RSA", 1024)
+	serverTemplate := mustCreateTemplate(t, Certificate{Id: "server", CommonName: "www.foo.se", AlternativeNames: []string{"www.foo.se"}, Usage: []string{"serverauth"}, PrivateKey: serverPriv, AllowWeakKey: true})
+	serverCert, err := TLSCertificate(Sign(serverTemplate, ca, key.PublicKey(serverPriv), caPriv), nil, serverPriv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clientPriv := key.GenerateKey("RSA", 1024)
+	clientTemplate := mustCreateTemplate(t, Certificate{Id: "client", CommonName: "client", Usage: []string{"clientauth"}, PrivateKey: clientPriv, AllowWeakKey: true})
+	clientCert, err := TLSCertificate(Sign(clientTemplate, ca, key.PublicKey(clientPriv), caPriv), nil, clientPriv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	serverConfig, err := ServerTLSConfig(caPEM, serverCert, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var peer string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer = r.TLS.PeerCertificates[0].Subject.CommonName
+	}))
+	server.TLS = serverConfig
+	server.StartTLS()
+	defer server.Close()
+
+	clientConfig, err := ClientTLSConfig(caPEM, clientCert, "www.foo.se")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+	resp, err := httpClient.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if peer != "client" {
+		t.Fatalf("got client certificate: %q, want client", peer)
+	}
+
+	noCertConfig, _ := ClientTLSConfig(caPEM, tls.Certificate{}, "www.foo.se")
+	noCertClient := &http.Client{Transport: &http.Transport{TLSClientConfig: noCertConfig}}
+	if resp, err := noCertClient.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("request without client certificate succeeded")
+	}
+}
+
+func TestTLSConfigInvalidCA(t *testing.T) {
+	if _, err := ServerTLSConfig([]byte("garbage"), tls.Certificate{}, true); err == nil {
+		t.Fatal("expected error for invalid CA PEM")
+	}
+}
@@ -0,0 +1,80 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"unicode/utf8"
+)
+
+// oidUPN is the Microsoft user principal name used for smart card logon.
+var oidUPN = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 3}
+
+// GeneralName tags of RFC 5280 section 4.2.1.6.
+const (
+	nameTagOther = 0
+	nameTagEmail = 1
+	nameTagDNS   = 2
+	nameTagURI   = 6
+	nameTagIP    = 7
+)
+
+// sanExtension encodes the subject alternative names of cert followed by upn, if set,
+// as an otherName, crypto/x509 has no support for otherName nor for choosing the
+// criticality. The DNS names, email addresses, IP addresses and URIs come in the same
+// order as crypto/x509 writes them. The extension is critical if critical is set or
+// the subject is empty.
+func sanExtension(cert *x509.Certificate, upn string, critical bool) (pkix.Extension, error) {
+	if !utf8.ValidString(upn) {
+		return pkix.Extension{}, fmt.Errorf("UPN %q is not valid UTF-8", upn)
+	}
+	var names []asn1.RawValue
+	for _, name := range cert.DNSNames {
+		names = append(names, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: nameTagDNS, Bytes: []byte(name)})
+	}
+	for _, email := range cert.EmailAddresses {
+		names = append(names, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: nameTagEmail, Bytes: []byte(email)})
+	}
+	for _, ip := range cert.IPAddresses {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		names = append(names, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: nameTagIP, Bytes: ip})
+	}
+	for _, uri := range cert.URIs {
+		names = append(names, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: nameTagURI, Bytes: []byte(uri.String())})
+	}
+	if upn != "" {
+		name, err := upnOtherName(upn)
+		if err != nil {
+			return pkix.Extension{}, err
+		}
+		names = append(names, name)
+	}
+	sans, err := asn1.Marshal(names)
+	if err != nil {
+		return pkix.Extension{}, err
+	}
+	// the names must be critical if the subject is empty, as crypto/x509 does
+	critical = critical || len(cert.Subject.ToRDNSequence()) == 0
+	return pkix.Extension{Id: oidSubjectAltName, Critical: critical, Value: sans}, nil
+}
+
+// upnOtherName encodes upn as an otherName GeneralName.
+func upnOtherName(upn string) (asn1.RawValue, error) {
+	value, err := asn1.MarshalWithParams(upn, "utf8")
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	// OtherName ::= SEQUENCE { type-id OBJECT IDENTIFIER, value [0] EXPLICIT ANY }
+	explicit, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: value})
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	typeID, err := asn1.Marshal(oidUPN)
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: nameTagOther, IsCompound: true, Bytes: append(typeID, explicit...)}, nil
+}
@@ -0,0 +1,92 @@
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+// generated with openssl req -addext "subjectAltName=DNS:www.foo.se,email:anna@foo.se,
+// IP:10.0.0.1,otherName:1.3.6.1.4.1.311.20.2.3;UTF8:anna@ad.foo.se"
+const opensslUPNSAN = "303f820a7777772e666f6f2e7365810b616e6e6140666f6f2e736587040a000001a01e060a2b060104018237140203a0100c0e616e6e614061642e666f6f2e7365"
+
+func TestUPNSubjectAltName(t *testing.T) {
+	ca, caPriv := createCA()
+	priv := key.GenerateKey("RSA", 1024)
+	template := mustCreateTemplate(t, Certificate{
+		Id:               "upn",
+		CommonName:       "www.foo.se",
+		AlternativeNames: []string{"www.foo.se"},
+		EmailAddresses:   []string{"anna@foo.se"},
+		IPAddresses:      []net.IP{net.ParseIP("10.0.0.1")},
+		UPN:              "anna@ad.foo.se",
+		PrivateKey:       priv,
+		AllowWeakKey:     true,
+		Usage:            []string{"signature", "clientauth"},
+	})
+	cert := parseCert(t, Sign(template, ca, key.PublicKey(priv), caPriv))
+	var sans [][]byte
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidSubjectAltName) {
+			sans = append(sans, ext.Value)
+		}
+	}
+	want, _ := hex.DecodeString(opensslUPNSAN)
+	if len(sans) != 1 || !bytes.Equal(sans[0], want) {
+		t.Fatalf("got: %x, want one extension with %x", sans, want)
+	}
+	if len(cert.DNSNames) != 1 || len(cert.EmailAddresses) != 1 || len(cert.IPAddresses) != 1 {
+		t.Fatalf("got: %v, want the other names kept", AllSANs(cert))
+	}
+}
+
+func TestUPNVersion1(t *testing.T) {
+	if _, err := NewCertificateTemplate(Certificate{Id: "upn", Version: 1, UPN: "anna@ad.foo.se", PrivateKey: key.GenerateKey("P256", 0)}); err == nil {
+		t.Fatal("expected error for a UPN in a version 1 certificate")
+	}
+}
+
+// extensionCritical returns if the extension with id is present in cert and critical.
+func extensionCritical(cert *x509.Certificate, id asn1.ObjectIdentifier) (found, critical bool) {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(id) {
+			return true, ext.Critical
+		}
+	}
+	return false, false
+}
+
+func TestSANCritical(t *testing.T) {
+	ca, caPriv := createCA()
+	priv := key.GenerateKey("RSA", 1024)
+	data := Certificate{
+		Id:               "san",
+		CommonName:       "www.foo.se",
+		AlternativeNames: []string{"www.foo.se", "foo.se"},
+		IPAddresses:      []net.IP{net.ParseIP("10.0.0.1")},
+		PrivateKey:       priv,
+		AllowWeakKey:     true,
+		Usage:            []string{"signature", "serverauth"},
+	}
+	plain := parseCert(t, Sign(mustCreateTemplate(t, data), ca, key.PublicKey(priv), caPriv))
+	if found, critical := extensionCritical(plain, oidSubjectAltName); !found || critical {
+		t.Fatalf("got SAN found %v critical %v, want a non-critical SAN by default", found, critical)
+	}
+	data.SANCritical = true
+	cert := parseCert(t, Sign(mustCreateTemplate(t, data), ca, key.PublicKey(priv), caPriv))
+	if found, critical := extensionCritical(cert, oidSubjectAltName); !found || !critical {
+		t.Fatalf("got SAN found %v critical %v, want a critical SAN", found, critical)
+	}
+	if found, critical := extensionCritical(cert, asn1.ObjectIdentifier{2, 5, 29, 15}); !found || !critical {
+		t.Fatalf("got key usage found %v critical %v, want a critical key usage", found, critical)
+	}
+	if !reflect.DeepEqual(AllSANs(cert), AllSANs(plain)) {
+		t.Fatalf("got SANs %v, want %v", AllSANs(cert), AllSANs(plain))
+	}
+}
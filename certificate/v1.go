@@ -0,0 +1,165 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// checkVersion returns an error if the certificate needs extensions that its version lacks.
+func checkVersion(data Certificate) error {
+	switch data.Version {
+	case 0, 3:
+		return nil
+	case 1:
+		if len(data.AlternativeNames) > 0 || len(data.IPAddresses) > 0 || len(data.EmailAddresses) > 0 || data.UPN != "" {
+			return errors.New("version 1 certificates can not have subject alternative names")
+		}
+		if len(data.IssuerURLs) > 0 || len(data.SCTList) > 0 || len(data.QCStatements) > 0 || len(data.UnknownExtKeyUsage) > 0 || len(data.DirectoryAttributes) > 0 {
+			return errors.New("version 1 certificates can not have extensions")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported certificate version: %d", data.Version)
+	}
+}
+
+// stripExtensions removes everything from the template that would become an extension.
+func stripExtensions(cert *x509.Certificate) {
+	cert.Version = 1
+	cert.KeyUsage = 0
+	cert.ExtKeyUsage = nil
+	cert.BasicConstraintsValid = false
+	cert.MaxPathLen = 0
+	cert.MaxPathLenZero = false
+	cert.SubjectKeyId = nil
+	cert.ExtraExtensions = nil
+}
+
+// v1 certificates have no version field, it defaults to v1.
+type tbsCertificateV1 struct {
+	SerialNumber       *big.Int
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           validityV1
+	Subject            asn1.RawValue
+	PublicKey          asn1.RawValue
+}
+
+type validityV1 struct {
+	NotBefore, NotAfter time.Time
+}
+
+type certificateV1 struct {
+	TBSCertificate     asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+var (
+	oidSHA1WithRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}
+	oidSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSHA384WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSHA512WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidECDSAWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidEd25519         = asn1.ObjectIdentifier{1, 3, 101, 112}
+)
+
+// v1SignatureAlgorithm returns the algorithm identifier and hash of alg. RSA
+// identifiers carry NULL parameters, ECDSA and Ed25519 ones none.
+func v1SignatureAlgorithm(alg x509.SignatureAlgorithm) (pkix.AlgorithmIdentifier, crypto.Hash, error) {
+	null := asn1.RawValue{Tag: asn1.TagNull}
+	switch alg {
+	case x509.SHA1WithRSA:
+		return pkix.AlgorithmIdentifier{Algorithm: oidSHA1WithRSA, Parameters: null}, crypto.SHA1, nil
+	case x509.SHA256WithRSA:
+		return pkix.AlgorithmIdentifier{Algorithm: oidSHA256WithRSA, Parameters: null}, crypto.SHA256, nil
+	case x509.SHA384WithRSA:
+		return pkix.AlgorithmIdentifier{Algorithm: oidSHA384WithRSA, Parameters: null}, crypto.SHA384, nil
+	case x509.SHA512WithRSA:
+		return pkix.AlgorithmIdentifier{Algorithm: oidSHA512WithRSA, Parameters: null}, crypto.SHA512, nil
+	case x509.ECDSAWithSHA1:
+		return pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA1}, crypto.SHA1, nil
+	case x509.ECDSAWithSHA256:
+		return pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256}, crypto.SHA256, nil
+	case x509.ECDSAWithSHA384:
+		return pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA384}, crypto.SHA384, nil
+	case x509.ECDSAWithSHA512:
+		return pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA512}, crypto.SHA512, nil
+	case x509.PureEd25519:
+		return pkix.AlgorithmIdentifier{Algorithm: oidEd25519}, crypto.Hash(0), nil
+	default:
+		return pkix.AlgorithmIdentifier{}, 0, fmt.Errorf("unsupported signature algorithm for a version 1 certificate: %v", alg)
+	}
+}
+
+// createV1Certificate encodes and signs a version 1 certificate, which the x509 package
+// is unable to create.
+func createV1Certificate(cert, signer *x509.Certificate, pub, priv interface{}) ([]byte, error) {
+	key, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported signer key type: %T", priv)
+	}
+	sigAlg := cert.SignatureAlgorithm
+	if sigAlg == x509.UnknownSignatureAlgorithm {
+		sigAlg = signatureAlgorithm("", key)
+	}
+	algorithm, hash, err := v1SignatureAlgorithm(sigAlg)
+	if err != nil {
+		return nil, err
+	}
+	spki, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := rawName(cert)
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := rawName(signer)
+	if err != nil {
+		return nil, err
+	}
+	tbs, err := asn1.Marshal(tbsCertificateV1{
+		SerialNumber:       cert.SerialNumber,
+		SignatureAlgorithm: algorithm,
+		Issuer:             asn1.RawValue{FullBytes: issuer},
+		Validity:           validityV1{cert.NotBefore.UTC().Truncate(time.Second), cert.NotAfter.UTC().Truncate(time.Second)},
+		Subject:            asn1.RawValue{FullBytes: subject},
+		PublicKey:          asn1.RawValue{FullBytes: spki},
+	})
+	if err != nil {
+		return nil, err
+	}
+	digest := tbs
+	if hash != 0 {
+		h := hash.New()
+		h.Write(tbs)
+		digest = h.Sum(nil)
+	}
+	signature, err := key.Sign(RandReader, digest, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign version 1 certificate: %v", err)
+	}
+	return asn1.Marshal(certificateV1{
+		TBSCertificate:     asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: algorithm,
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
+}
+
+// rawName returns the DER encoded subject of cert.
+func rawName(cert *x509.Certificate) ([]byte, error) {
+	if len(cert.RawSubject) > 0 {
+		return cert.RawSubject, nil
+	}
+	return asn1.Marshal(cert.Subject.ToRDNSequence())
+}
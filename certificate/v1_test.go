@@ -0,0 +1,62 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"net"
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestCreateV1Certificate(t *testing.T) {
+	for _, keyType := range []string{"RSA", "P256"} {
+		caPriv := key.GenerateKey(keyType, 1024)
+		ca := mustCreateTemplate(t, Certificate{Id: "ca", CommonName: "ca", CA: true, PrivateKey: caPriv, AllowWeakKey: true})
+		caCert := parseCert(t, Sign(ca, ca, key.PublicKey(caPriv), caPriv))
+		priv := key.GenerateKey(keyType, 1024)
+		template := mustCreateTemplate(t, Certificate{Id: "legacy", CommonName: "legacy", Version: 1, PrivateKey: priv, AllowWeakKey: true})
+		cert := parseCert(t, Sign(template, caCert, key.PublicKey(priv), caPriv))
+		if cert.Version != 1 {
+			t.Fatalf("%s: got version %d, want 1", keyType, cert.Version)
+		}
+		if len(cert.Extensions) != 0 {
+			t.Fatalf("%s: got extensions %v", keyType, cert.Extensions)
+		}
+		if err := cert.CheckSignatureFrom(caCert); err != nil {
+			t.Fatalf("%s: signature does not verify: %v", keyType, err)
+		}
+		if cert.Subject.CommonName != "legacy" || cert.Issuer.CommonName != "ca" {
+			t.Fatalf("%s: got subject %v issued by %v", keyType, cert.Subject, cert.Issuer)
+		}
+	}
+}
+
+func TestCreateV1SelfSigned(t *testing.T) {
+	priv := key.GenerateKey("RSA", 1024)
+	template := mustCreateTemplate(t, Certificate{Id: "legacy", CommonName: "legacy", Version: 1, PrivateKey: priv, AllowWeakKey: true})
+	cert := parseCert(t, Sign(template, template, key.PublicKey(priv), priv))
+	if cert.Version != 1 || cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) != nil {
+		t.Fatalf("got version %d, want a valid self signed version 1 certificate", cert.Version)
+	}
+}
+
+func TestV1RejectsSANs(t *testing.T) {
+	priv := key.GenerateKey("P256", 0)
+	for _, data := range []Certificate{
+		{CommonName: "www.foo.se", Version: 1, AlternativeNames: []string{"www.foo.se"}, PrivateKey: priv, AllowWeakKey: true},
+		{CommonName: "www.foo.se", Version: 1, IPAddresses: []net.IP{net.ParseIP("10.0.0.1")}, PrivateKey: priv, AllowWeakKey: true},
+		{CommonName: "www.foo.se", Version: 2, PrivateKey: priv, AllowWeakKey: true},
+	} {
+		if _, err := NewCertificateTemplate(data); err == nil {
+			t.Fatalf("expected error for version %d with %v %v", data.Version, data.AlternativeNames, data.IPAddresses)
+		}
+	}
+}
+
+func parseCert(t *testing.T, der []byte) *x509.Certificate {
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert
+}
@@ -0,0 +1,55 @@
+package certificate
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+)
+
+// VerifyWithSystemRoots verifies the certificate against the trust store of the
+// operating system instead of a custom CA.
+func VerifyWithSystemRoots(dnsName string, clientBytes []byte) error {
+	clientCert, err := x509.ParseCertificate(clientBytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse certificate: %v", err)
+	}
+	opts := x509.VerifyOptions{
+		DNSName: dnsName,
+	}
+	if _, err := clientCert.Verify(opts); err != nil {
+		return fmt.Errorf("could not verify certificate %v against system roots: %v", clientCert.Subject.CommonName, err)
+	}
+	return nil
+}
+
+// VerifyWithCRL verifies the certificate chain and then checks that the serial of the
+// certificate is not in the revocation list. The revocation list must be signed by the
+// issuer of the certificate and must not be past its next update, it may be PEM or
+// DER encoded. A revoked certificate gives a *RevokedError.
+func VerifyWithCRL(dnsName string, caBytes, interCaBytes, clientBytes, crlBytes []byte) error {
+	chains, err := verifyChain(dnsName, caBytes, interCaBytes, clientBytes, nil)
+	if err != nil {
+		return err
+	}
+	crl, err := ParseCRL(crlBytes)
+	if err != nil {
+		return err
+	}
+	leaf := chains[0][0]
+	issuer := leaf
+	if len(chains[0]) > 1 {
+		issuer = chains[0][1]
+	}
+	if err := VerifyCRLSignature(crl, issuer); err != nil {
+		return err
+	}
+	if !crl.NextUpdate.IsZero() && time.Now().After(crl.NextUpdate) {
+		return fmt.Errorf("CRL expired at %v", crl.NextUpdate)
+	}
+	for _, r := range ListRevoked(crl) {
+		if r.SerialNumber.Cmp(leaf.SerialNumber) == 0 && r.Reason != ReasonRemoveFromCRL {
+			return &RevokedError{SerialNumber: r.SerialNumber, RevokedAt: r.RevocationTime, Reason: r.Reason}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestVerifyWithSystemRootsUnknownCA(t *testing.T) {
+	ca, caPriv := createCA()
+	client, clientPriv := createClient()
+	clientBytes := Sign(client, ca, key.PublicKey(clientPriv), caPriv)
+	if err := VerifyWithSystemRoots("www.baz.se", clientBytes); err == nil {
+		t.Fatal("certificate from a private CA verified against system roots")
+	}
+}
+
+func TestVerifyWithSystemRootsInvalidInput(t *testing.T) {
+	if err := VerifyWithSystemRoots("www.baz.se", []byte("garbage")); err == nil {
+		t.Fatal("expected error for invalid certificate")
+	}
+}
+
+func createCRL(t *testing.T, ca *x509.Certificate, caPriv interface{}, nextUpdate time.Time, serials ...*big.Int) []byte {
+	var entries []x509.RevocationListEntry
+	for _, serial := range serials {
+		entries = append(entries, x509.RevocationListEntry{SerialNumber: serial, RevocationTime: time.Now()})
+	}
+	template := &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                time.Now().Add(-time.Hour),
+		NextUpdate:                nextUpdate,
+		RevokedCertificateEntries: entries,
+	}
+	crl, err := x509.CreateRevocationList(rand.Reader, template, ca, caPriv.(crypto.Signer))
+	if err != nil {
+		t.Fatalf("failed to create CRL: %v", err)
+	}
+	return crl
+}
+
+func TestVerifyWithCRL(t *testing.T) {
+	ca, caPriv := createCA()
+	caBytes := Sign(ca, ca, key.PublicKey(caPriv), caPriv)
+	interCa, interCaPriv := createInterCA()
+	interCaBytes := Sign(interCa, ca, key.PublicKey(interCaPriv), caPriv)
+	client, clientPriv := createClient()
+	clientBytes := Sign(client, interCa, key.PublicKey(clientPriv), interCaPriv)
+
+	validCRL := createCRL(t, interCa, interCaPriv, time.Now().Add(time.Hour), big.NewInt(42))
+	if err := VerifyWithCRL("www.baz.se", caBytes, interCaBytes, clientBytes, validCRL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	revokedCRL := createCRL(t, interCa, interCaPriv, time.Now().Add(time.Hour), big.NewInt(42), client.SerialNumber)
+	if err := VerifyWithCRL("www.baz.se", caBytes, interCaBytes, clientBytes, revokedCRL); err == nil {
+		t.Fatal("revoked certificate verified")
+	}
+}
+
+func TestVerifyWithCRLExpired(t *testing.T) {
+	ca, caPriv := createCA()
+	caBytes := Sign(ca, ca, key.PublicKey(caPriv), caPriv)
+	client, clientPriv := createClient()
+	clientBytes := Sign(client, ca, key.PublicKey(clientPriv), caPriv)
+	expiredCRL := createCRL(t, ca, caPriv, time.Now().Add(-time.Minute))
+	if err := VerifyWithCRL("www.baz.se", caBytes, nil, clientBytes, expiredCRL); err == nil {
+		t.Fatal("expected error for expired CRL")
+	}
+}
+
+func TestVerifyWithCRLWrongIssuer(t *testing.T) {
+	ca, caPriv := createCA()
+	caBytes := Sign(ca, ca, key.PublicKey(caPriv), caPriv)
+	other, otherPriv := createInterCA()
+	client, clientPriv := createClient()
+	clientBytes := Sign(client, ca, key.PublicKey(clientPriv), caPriv)
+	otherCRL := createCRL(t, other, otherPriv, time.Now().Add(time.Hour))
+	if err := VerifyWithCRL("www.baz.se", caBytes, nil, clientBytes, otherCRL); err == nil {
+		t.Fatal("expected error for CRL from another issuer")
+	}
+}
@@ -0,0 +1,94 @@
+package certificate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	maxDNSNameLength  = 253
+	maxDNSLabelLength = 63
+)
+
+// validateDNSNames checks that every DNS name is at most 253 characters with labels of
+// at most 63 letters, digits and inner hyphens, underscores are also accepted if
+// allowUnderscores is set. All offending names are listed in the error. Wildcards
+// must be a single asterisk that is the whole left-most label, not followed by an IP
+// address and, if checkPublicSuffix is set, not covering a public suffix such as *.com.
+func validateDNSNames(names []string, checkPublicSuffix, allowUnderscores bool) error {
+	var invalid []string
+	for _, name := range names {
+		err := checkDNSNameSyntax(name, allowUnderscores)
+		if err == nil && strings.Contains(name, "*") {
+			err = checkWildcard(name, checkPublicSuffix)
+		}
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%q (%v)", name, err))
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid DNS SANs: %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
+// checkWildcard checks the placement of the asterisk in the wildcard name.
+func checkWildcard(name string, checkPublicSuffix bool) error {
+	if strings.Count(name, "*") != 1 || !strings.HasPrefix(name, "*.") {
+		return errors.New("the asterisk must be the whole left-most label")
+	}
+	base := strings.TrimPrefix(name, "*.")
+	if base == "" {
+		return errors.New("no domain after the asterisk")
+	}
+	if isNumericName(base) {
+		return errors.New("wildcards can not be combined with IP addresses")
+	}
+	if checkPublicSuffix {
+		if suffix, _ := publicsuffix.PublicSuffix(base); suffix == base {
+			return fmt.Errorf("%s is a public suffix", base)
+		}
+	}
+	return nil
+}
+
+// isNumericName reports if every label of name is a number, as in an IPv4 address.
+func isNumericName(name string) bool {
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || strings.Trim(label, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDNSNameSyntax checks the length and characters of name, a left-most "*" label
+// is left to the wildcard checks.
+func checkDNSNameSyntax(name string, allowUnderscores bool) error {
+	if len(name) > maxDNSNameLength {
+		return fmt.Errorf("longer than %d characters", maxDNSNameLength)
+	}
+	for i, label := range strings.Split(name, ".") {
+		if i == 0 && strings.Contains(label, "*") {
+			continue
+		}
+		if label == "" {
+			return errors.New("empty label")
+		}
+		if len(label) > maxDNSLabelLength {
+			return fmt.Errorf("label longer than %d characters", maxDNSLabelLength)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' && allowUnderscores) {
+				return fmt.Errorf("invalid character %q", r)
+			}
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return errors.New("label starts or ends with a hyphen")
+		}
+	}
+	return nil
+}
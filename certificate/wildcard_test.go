@@ -0,0 +1,109 @@
+package certificate
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ignalina/certificateBar/v2/key"
+)
+
+func TestValidateDNSNames(t *testing.T) {
+	valid := []string{"www.foo.se", "*.foo.se", "*.foo.co.uk"}
+	if err := validateDNSNames(valid, true, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	invalid := []string{"*.*.foo.se", "foo.*.foo.se", "f*.foo.se", "*", "*.", "*.10.0.0.1"}
+	for _, name := range invalid {
+		err := validateDNSNames([]string{"www.foo.se", name}, false, false)
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Fatalf("%s: got: %v, want error naming the SAN", name, err)
+		}
+	}
+	for _, name := range []string{"*.com", "*.co.uk"} {
+		if err := validateDNSNames([]string{name}, false, false); err != nil {
+			t.Fatalf("%s: unexpected error without public suffix check: %v", name, err)
+		}
+		if err := validateDNSNames([]string{name}, true, false); err == nil {
+			t.Fatalf("%s: expected error for a public suffix", name)
+		}
+	}
+}
+
+func TestValidateDNSNamesListsAllOffenders(t *testing.T) {
+	names := []string{"*.*.foo.se", "-foo.se", "ok.foo.se", "*.com", "foo..se"}
+	err := validateDNSNames(names, true, false)
+	if err == nil {
+		t.Fatal("expected error for invalid DNS names")
+	}
+	for _, name := range []string{"*.*.foo.se", "-foo.se", "*.com", "foo..se"} {
+		if !strings.Contains(err.Error(), strconv.Quote(name)) {
+			t.Fatalf("got: %v, want error naming %s", err, name)
+		}
+	}
+	if strings.Contains(err.Error(), "ok.foo.se") {
+		t.Fatalf("got: %v, valid name listed", err)
+	}
+}
+
+func TestCreateCertificateTemplateRejectsWildcard(t *testing.T) {
+	data := Certificate{CommonName: "www.foo.se", AlternativeNames: []string{"*.*.foo.se"}, PrivateKey: key.GenerateKey("P256", 0)}
+	if _, err := NewCertificateTemplate(data); err == nil || !strings.Contains(err.Error(), "*.*.foo.se") {
+		t.Fatalf("got: %v, want error naming *.*.foo.se", err)
+	}
+}
+
+func TestCreateCertificateTemplateRejectsInvalidDNSNames(t *testing.T) {
+	longLabel := strings.Repeat("a", 64) + ".foo.se"
+	longName := strings.Repeat("abcdefghi.", 26) + "se"
+	data := Certificate{
+		CommonName:       "www.foo.se",
+		AlternativeNames: []string{longLabel, "foo_bar.foo.se", longName, "-foo.se", "ok.foo.se"},
+		PrivateKey:       key.GenerateKey("P256", 0),
+	}
+	_, err := NewCertificateTemplate(data)
+	if err == nil {
+		t.Fatal("expected error for invalid DNS names")
+	}
+	for _, name := range []string{longLabel, "foo_bar.foo.se", longName, "-foo.se"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Fatalf("got: %v, want error naming %s", err, name)
+		}
+	}
+	if strings.Contains(err.Error(), "ok.foo.se") {
+		t.Fatalf("got: %v, valid name listed", err)
+	}
+	label := strings.Repeat("a", 63)
+	if err := validateDNSNames([]string{label + ".foo.se", "xn--rksmrgs-5wao1o.se"}, false, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCreateCertificateTemplateUnderscores(t *testing.T) {
+	data := Certificate{
+		CommonName:       "my server.local",
+		AlternativeNames: []string{"_ldap._tcp.foo.se", "foo_bar.foo.se"},
+		PrivateKey:       key.GenerateKey("P256", 0),
+	}
+	_, err := NewCertificateTemplate(data)
+	for _, name := range []string{"my server.local", "_ldap._tcp.foo.se", "foo_bar.foo.se"} {
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Fatalf("got: %v, want error naming %s", err, name)
+		}
+	}
+	data.AllowUnderscores = true
+	if _, err := NewCertificateTemplate(data); err == nil || !strings.Contains(err.Error(), "my server.local") || strings.Contains(err.Error(), "foo_bar") {
+		t.Fatalf("got: %v, want error naming only the common name", err)
+	}
+	data.CommonName = "foo_bar.foo.se"
+	cert, err := NewCertificateTemplate(data)
+	if err != nil {
+		t.Fatalf("unexpected error with underscores allowed: %v", err)
+	}
+	if !FromX509(cert).AllowUnderscores {
+		t.Fatal("AllowUnderscores not recovered by FromX509")
+	}
+	if err := validateDNSNames([]string{"-foo_.foo.se"}, false, true); err == nil {
+		t.Fatal("expected error for a label starting with a hyphen")
+	}
+}
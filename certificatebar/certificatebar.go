@@ -1,6 +1,6 @@
 package certificatebar
 
-import "github.com/chrjoh/certificateBar/assember"
+import "github.com/ignalina/certificateBar/v2/assember"
 
 func Handler(config string) {
 	certs := assembler.Generate(config)
@@ -3,6 +3,10 @@ module github.com/ignalina/certificateBar/v2
 go 1.23.1
 
 require (
-	github.com/chrjoh/certificateBar v0.0.0-20151122195015-3c86fcff64fb
+	github.com/pavlo-v-chernykh/keystore-go/v4 v4.5.0
+	golang.org/x/crypto v0.41.0
+	golang.org/x/net v0.43.0
 	gopkg.in/yaml.v2 v2.4.0
 )
+
+require golang.org/x/text v0.28.0 // indirect
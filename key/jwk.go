@@ -0,0 +1,156 @@
+package key
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// jwk is a JSON Web Key as defined in RFC 7517, members are omitted if empty.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	D   string `json:"d,omitempty"`
+	P   string `json:"p,omitempty"`
+	Q   string `json:"q,omitempty"`
+	DP  string `json:"dp,omitempty"`
+	DQ  string `json:"dq,omitempty"`
+	QI  string `json:"qi,omitempty"`
+}
+
+// ToJWK returns pub, an RSA, ECDSA or Ed25519 public key, as an RFC 7517 JSON Web Key.
+func ToJWK(pub interface{}) ([]byte, error) {
+	k, err := publicJWK(pub)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(k)
+}
+
+// ToJWKWithKid is ToJWK with the kid member set to kid. An empty kid defaults to the
+// base64url encoded RFC 5280 method 1 key identifier, the SHA-1 hash of the
+// subjectPublicKey, which is the subject key identifier the certificate package puts
+// in certificates with the default SKIDSHA1 method. Pass JWKThumbprint(pub) for an
+// RFC 7638 kid.
+func ToJWKWithKid(pub interface{}, kid string) ([]byte, error) {
+	k, err := publicJWK(pub)
+	if err != nil {
+		return nil, err
+	}
+	if kid == "" {
+		ski, err := SubjectKeyId(pub)
+		if err != nil {
+			return nil, err
+		}
+		kid = base64.RawURLEncoding.EncodeToString(ski)
+	}
+	k.Kid = kid
+	return json.Marshal(k)
+}
+
+// PublicKeyToJWK is ToJWKWithKid, kept for callers written against it.
+func PublicKeyToJWK(pub interface{}, kid string) ([]byte, error) {
+	return ToJWKWithKid(pub, kid)
+}
+
+// PrivateToJWK returns priv, an RSA, ECDSA or Ed25519 private key, as a JSON Web Key
+// including the private members and a kid set to the thumbprint of the public key.
+// The output is as secret as the key itself.
+func PrivateToJWK(priv interface{}) ([]byte, error) {
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unknown private key type: %T", priv)
+	}
+	k, err := publicJWK(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	if k.Kid, err = k.thumbprint(); err != nil {
+		return nil, err
+	}
+	switch priv := priv.(type) {
+	case *rsa.PrivateKey:
+		if len(priv.Primes) != 2 {
+			return nil, fmt.Errorf("RSA keys with %d primes are not supported", len(priv.Primes))
+		}
+		priv.Precompute()
+		k.D = encodeBigInt(priv.D)
+		k.P = encodeBigInt(priv.Primes[0])
+		k.Q = encodeBigInt(priv.Primes[1])
+		k.DP = encodeBigInt(priv.Precomputed.Dp)
+		k.DQ = encodeBigInt(priv.Precomputed.Dq)
+		k.QI = encodeBigInt(priv.Precomputed.Qinv)
+	case *ecdsa.PrivateKey:
+		size := (priv.Curve.Params().BitSize + 7) / 8
+		k.D = base64.RawURLEncoding.EncodeToString(priv.D.FillBytes(make([]byte, size)))
+	case ed25519.PrivateKey:
+		k.D = base64.RawURLEncoding.EncodeToString(priv.Seed())
+	default:
+		return nil, fmt.Errorf("unknown private key type: %T", priv)
+	}
+	return json.Marshal(k)
+}
+
+// JWKThumbprint returns the RFC 7638 SHA-256 thumbprint of pub, base64url encoded.
+func JWKThumbprint(pub interface{}) (string, error) {
+	k, err := publicJWK(pub)
+	if err != nil {
+		return "", err
+	}
+	return k.thumbprint()
+}
+
+func publicJWK(pub interface{}) (*jwk, error) {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		return &jwk{Kty: "RSA", N: encodeBigInt(pub.N), E: encodeBigInt(big.NewInt(int64(pub.E)))}, nil
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		// coordinates are padded to the size of the curve, RFC 7518 section 6.2.1.2
+		return &jwk{
+			Kty: "EC",
+			Crv: pub.Curve.Params().Name,
+			X:   base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size))),
+			Y:   base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case ed25519.PublicKey:
+		return &jwk{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(pub)}, nil
+	default:
+		return nil, fmt.Errorf("unknown public key type: %T", pub)
+	}
+}
+
+// thumbprint hashes the required public members in lexicographic order, as RFC 7638 requires.
+func (k *jwk) thumbprint() (string, error) {
+	var members map[string]string
+	switch k.Kty {
+	case "RSA":
+		members = map[string]string{"e": k.E, "kty": k.Kty, "n": k.N}
+	case "EC":
+		members = map[string]string{"crv": k.Crv, "kty": k.Kty, "x": k.X, "y": k.Y}
+	default:
+		members = map[string]string{"crv": k.Crv, "kty": k.Kty, "x": k.X}
+	}
+	// encoding/json writes map keys sorted and without whitespace
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func encodeBigInt(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
@@ -0,0 +1,167 @@
+package key
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"testing"
+)
+
+func decodeBase64URL(t *testing.T, s string) []byte {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("failed to decode %q: %v", s, err)
+	}
+	return b
+}
+
+// the example key of RFC 7638 section 3.1
+var rfc7638Modulus = "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"
+
+func TestToJWKRSA(t *testing.T) {
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(decodeBase64URL(t, rfc7638Modulus)), E: 65537}
+	kid, err := JWKThumbprint(pub)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := ToJWKWithKid(pub, kid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got map[string]string
+	json.Unmarshal(data, &got)
+	want := map[string]string{"kty": "RSA", "n": rfc7638Modulus, "e": "AQAB", "kid": "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"}
+	if len(got) != len(want) {
+		t.Fatalf("got: %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("%s: got %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+// the example key of RFC 8037 appendix A
+func TestToJWKEd25519(t *testing.T) {
+	pub := ed25519.PublicKey(decodeBase64URL(t, "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"))
+	data, err := ToJWK(pub)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`; string(data) != want {
+		t.Fatalf("got: %s, want %s", data, want)
+	}
+	if kid, _ := JWKThumbprint(pub); kid != "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k" {
+		t.Fatalf("got thumbprint %s", kid)
+	}
+}
+
+func TestToJWKECDSA(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		priv, _ := ecdsa.GenerateKey(curve, RandReader)
+		data, err := PrivateToJWK(priv)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", curve.Params().Name, err)
+		}
+		var got map[string]string
+		json.Unmarshal(data, &got)
+		size := (curve.Params().BitSize + 7) / 8
+		x, y, d := decodeBase64URL(t, got["x"]), decodeBase64URL(t, got["y"]), decodeBase64URL(t, got["d"])
+		if got["kty"] != "EC" || got["crv"] != curve.Params().Name || len(x) != size || len(y) != size {
+			t.Fatalf("got: %v", got)
+		}
+		if new(big.Int).SetBytes(x).Cmp(priv.X) != 0 || new(big.Int).SetBytes(y).Cmp(priv.Y) != 0 || new(big.Int).SetBytes(d).Cmp(priv.D) != 0 {
+			t.Fatalf("%s: coordinates or private key differ", curve.Params().Name)
+		}
+		if kid, _ := JWKThumbprint(&priv.PublicKey); got["kid"] != kid {
+			t.Fatalf("got kid %s, want %s", got["kid"], kid)
+		}
+	}
+}
+
+func TestPrivateToJWKRSA(t *testing.T) {
+	priv := GenerateKey("RSA", 1024).(*rsa.PrivateKey)
+	data, err := PrivateToJWK(priv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got map[string]string
+	json.Unmarshal(data, &got)
+	for name, want := range map[string]*big.Int{"d": priv.D, "p": priv.Primes[0], "q": priv.Primes[1], "qi": priv.Precomputed.Qinv} {
+		if new(big.Int).SetBytes(decodeBase64URL(t, got[name])).Cmp(want) != 0 {
+			t.Fatalf("%s differs", name)
+		}
+	}
+	if _, err := ToJWK("not a key"); err == nil {
+		t.Fatal("expected error for an unknown key type")
+	}
+}
+
+func TestToJWKWithKidDefault(t *testing.T) {
+	tests := map[string][]string{
+		opensslRSACertPem:   {"kty", "kid", "n", "e"},
+		opensslECDSACertPem: {"kty", "kid", "crv", "x", "y"},
+	}
+	for certPem, members := range tests {
+		block, _ := pem.Decode([]byte(certPem))
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatalf("failed to parse certificate: %v", err)
+		}
+		data, err := ToJWKWithKid(cert.PublicKey, "")
+		if err != nil {
+			t.Fatalf("%T: unexpected error: %v", cert.PublicKey, err)
+		}
+		var got map[string]string
+		json.Unmarshal(data, &got)
+		if len(got) != len(members) {
+			t.Fatalf("%T: got: %v, want members %v", cert.PublicKey, got, members)
+		}
+		for _, m := range members {
+			if got[m] == "" {
+				t.Fatalf("%T: member %s is missing", cert.PublicKey, m)
+			}
+		}
+		// openssl uses the SHA-1 method for the subject key identifier
+		if want := base64.RawURLEncoding.EncodeToString(cert.SubjectKeyId); got["kid"] != want {
+			t.Fatalf("%T: got kid %s, want the SKI %s", cert.PublicKey, got["kid"], want)
+		}
+		switch pub := cert.PublicKey.(type) {
+		case *rsa.PublicKey:
+			if got["kty"] != "RSA" || new(big.Int).SetBytes(decodeBase64URL(t, got["n"])).Cmp(pub.N) != 0 || got["e"] != "AQAB" {
+				t.Fatalf("got: %v, want the RSA key", got)
+			}
+		case *ecdsa.PublicKey:
+			if got["kty"] != "EC" || got["crv"] != "P-256" || new(big.Int).SetBytes(decodeBase64URL(t, got["x"])).Cmp(pub.X) != 0 || new(big.Int).SetBytes(decodeBase64URL(t, got["y"])).Cmp(pub.Y) != 0 {
+				t.Fatalf("got: %v, want the P-256 key", got)
+			}
+		}
+	}
+	data, _ := ToJWKWithKid(PublicKey(GenerateKey("P256", 0)), "signing-2026")
+	var got map[string]string
+	json.Unmarshal(data, &got)
+	if got["kid"] != "signing-2026" {
+		t.Fatalf("got kid %q, want signing-2026", got["kid"])
+	}
+}
+
+func TestPublicKeyToJWK(t *testing.T) {
+	pub := PublicKey(GenerateKey("P256", 0))
+	for _, kid := range []string{"", "key-1"} {
+		got, err := PublicKeyToJWK(pub, kid)
+		if err != nil {
+			t.Fatalf("kid %q: unexpected error: %v", kid, err)
+		}
+		want, _ := ToJWKWithKid(pub, kid)
+		if !bytes.Equal(got, want) {
+			t.Fatalf("kid %q: got: %s, want %s", kid, got, want)
+		}
+	}
+}
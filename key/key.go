@@ -1,39 +1,58 @@
 package key
 
 import (
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/asn1"
+	"encoding/base64"
 	"encoding/pem"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"math/big"
 	"os"
+	"strings"
 )
 
+// RandReader is the source of randomness for key generation. Tests may replace it
+// with a deterministic reader, note that the standard library only guarantees
+// reproducible keys from a custom reader for some key types. Production code must
+// keep the crypto/rand default. Key generation is safe for concurrent use as long as
+// RandReader is, which deterministic test readers usually are not.
+var RandReader io.Reader = rand.Reader
+
 // rsaPublicKey reflects the ASN.1 structure of a PKCS#1 public key.
 type rsaPublicKey struct {
 	N *big.Int
 	E int
 }
 
+// PublicKey returns the public key of privateKey, or nil if the type of privateKey is
+// unknown.
 func PublicKey(privateKey interface{}) interface{} {
-	var publicKey interface{}
 	switch key := privateKey.(type) {
 	case *rsa.PrivateKey:
 		return &key.PublicKey
 	case *ecdsa.PrivateKey:
 		return &key.PublicKey
+	case crypto.Signer:
+		// keys kept outside of memory, e.g. in a HSM
+		return key.Public()
 	default:
-		log.Fatal("Could not get public key\n")
-		return publicKey
+		logger.Printf("could not get the public key of a %T", privateKey)
+		return nil
 	}
 }
 
+// PublicKeyBitArray returns the content of the subjectPublicKey BIT STRING in the
+// SubjectPublicKeyInfo of pub, the input to the RFC 5280 method 1 key identifier.
 func PublicKeyBitArray(pub interface{}) (publicKeyBytes []byte, err error) {
 	switch pub := pub.(type) {
 	case *rsa.PublicKey:
@@ -43,51 +62,255 @@ func PublicKeyBitArray(pub interface{}) (publicKeyBytes []byte, err error) {
 		})
 	case *ecdsa.PublicKey:
 		publicKeyBytes = elliptic.Marshal(pub.Curve, pub.X, pub.Y)
+	case ed25519.PublicKey:
+		publicKeyBytes = append([]byte{}, pub...)
 	default:
-		return nil, errors.New("x509: only RSA and ECDSA public keys supported")
+		return nil, errors.New("x509: only RSA, ECDSA and Ed25519 public keys supported")
 	}
 	return publicKeyBytes, nil
 }
 
+// SubjectKeyId returns the RFC 5280 method 1 key identifier of pub, the SHA-1 hash of
+// PublicKeyBitArray.
+func SubjectKeyId(pub interface{}) ([]byte, error) {
+	bits, err := PublicKeyBitArray(pub)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum(bits)
+	return sum[:], nil
+}
+
+// SPKIPin returns the base64 encoded SHA-256 hash of the DER encoded SubjectPublicKeyInfo,
+// the pin format used by HPKP and curl --pinnedpubkey.
+func SPKIPin(pub interface{}) (string, error) {
+	spki, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(spki)
+	return base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// MarshalPublicPem returns pub, an RSA, ECDSA or Ed25519 public key, as a PEM encoded
+// SubjectPublicKeyInfo, the PUBLIC KEY format read by openssl pkey -pubin.
+func MarshalPublicPem(pub interface{}) ([]byte, error) {
+	switch pub.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+	default:
+		return nil, fmt.Errorf("unknown public key type: %T", pub)
+	}
+	spki, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: spki}), nil
+}
+
+// WritePublicPem writes pub to w the same way as MarshalPublicPem encodes it.
+func WritePublicPem(w io.Writer, pub interface{}) error {
+	pemBytes, err := MarshalPublicPem(pub)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(pemBytes)
+	return err
+}
+
+// MinRSAKeyBits is the shortest RSA key Generate and GenerateRSA create without
+// AllowWeak. It is also the default floor of the certificate package, which does not
+// create templates for shorter RSA keys unless AllowWeakKey is set and lints them.
+const MinRSAKeyBits = 2048
+
+// KeySpec describes a key to generate from configuration.
+type KeySpec struct {
+	// Type is RSA or the name of an ECDSA curve, P-256, P-384 or P-521. The names
+	// used in the config file, such as P256, are accepted as well.
+	Type string
+	// Bits is the length of an RSA key, 2048 if not set.
+	Bits int
+	// AllowWeak permits RSA keys shorter than MinRSAKeyBits, e.g. to speed up tests.
+	AllowWeak bool
+}
+
+// Generate creates a private key as described by spec.
+func Generate(spec KeySpec) (crypto.Signer, error) {
+	if spec.Type != "RSA" {
+		return GenerateECDSA(spec.Type)
+	}
+	bits := spec.Bits
+	if bits == 0 {
+		bits = 2048
+	}
+	if bits < MinRSAKeyBits && !spec.AllowWeak {
+		return nil, fmt.Errorf("RSA key length %d is shorter than %d bits", bits, MinRSAKeyBits)
+	}
+	return rsa.GenerateKey(RandReader, bits)
+}
+
+// GenerateRSA creates an RSA private key, bits must be at least MinRSAKeyBits.
+func GenerateRSA(bits int) (*rsa.PrivateKey, error) {
+	if bits < MinRSAKeyBits {
+		return nil, fmt.Errorf("RSA key length %d is shorter than %d bits", bits, MinRSAKeyBits)
+	}
+	return rsa.GenerateKey(RandReader, bits)
+}
+
+// GenerateECDSA creates an ECDSA private key on the named curve, P-256, P-384 or P-521.
+// P-224 is accepted for existing configurations.
+func GenerateECDSA(curve string) (*ecdsa.PrivateKey, error) {
+	var c elliptic.Curve
+	switch curve {
+	case "P-224", "P224":
+		c = elliptic.P224()
+	case "P-256", "P256":
+		c = elliptic.P256()
+	case "P-384", "P384":
+		c = elliptic.P384()
+	case "P-521", "P521":
+		c = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unknown curve: %q", curve)
+	}
+	return ecdsa.GenerateKey(c, RandReader)
+}
+
+// GenerateKey creates a private key of keyType, RSA, P224, P256, P384 or P521. It
+// panics on an unknown type or if the key can not be generated, use Generate to get
+// an error instead.
 // TODO: use struct for this so that we do not have unused arguments
 func GenerateKey(keyType string, rsaBitLength int) interface{} {
 	var privateKey interface{}
 	var err error
 	switch keyType {
 	case "RSA":
-		privateKey, err = rsa.GenerateKey(rand.Reader, rsaBitLength)
+		privateKey, err = rsa.GenerateKey(RandReader, rsaBitLength)
 	case "P224":
-		privateKey, err = ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+		privateKey, err = ecdsa.GenerateKey(elliptic.P224(), RandReader)
 	case "P256":
-		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), RandReader)
 	case "P384":
-		privateKey, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		privateKey, err = ecdsa.GenerateKey(elliptic.P384(), RandReader)
 	case "P521":
-		privateKey, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+		privateKey, err = ecdsa.GenerateKey(elliptic.P521(), RandReader)
 	default:
-		log.Fatalf("Unrecognized key type: %v", keyType)
+		panic(fmt.Sprintf("unrecognized key type: %v", keyType))
 	}
 	if err != nil {
-		log.Fatalf("failed to generate private key: %s", err)
+		panic(fmt.Sprintf("failed to generate private key: %v", err))
 	}
 	return privateKey
 }
 
-func WritePrivateKeyToPemFile(key interface{}, fileName string) {
-	keyFile, err := os.Create(fileName)
-	defer keyFile.Close()
-	if err != nil {
-		log.Fatalf("Failed to open %s for writing private key: %s\n", fileName, err)
-	}
+// EncodePrivateKeyPem returns the private key PEM encoded the same way as WritePrivateKeyToPemFile writes it.
+func EncodePrivateKeyPem(key interface{}) ([]byte, error) {
 	switch k := key.(type) {
 	case *rsa.PrivateKey:
-		pem.Encode(keyFile, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)})
-		fmt.Printf("wrote RSA private key %s to file\n", fileName)
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}), nil
 	case *ecdsa.PrivateKey:
-		ecKey, _ := x509.MarshalECPrivateKey(k)
-		pem.Encode(keyFile, &pem.Block{Type: "EC PRIVATE KEY", Bytes: ecKey})
-		fmt.Printf("wrote EC private key %s to file\n", fileName)
+		ecKey, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecKey}), nil
+	case ed25519.PrivateKey:
+		// Ed25519 keys only have a PKCS#8 encoding
+		pkcs8, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), nil
 	default:
-		log.Printf("Uknown key type: %v to write to file", key)
+		return nil, fmt.Errorf("unknown key type: %T", key)
+	}
+}
+
+// KeyFormatError is returned by ParsePrivateKeyPem when none of the private key
+// formats could parse the key.
+type KeyFormatError struct {
+	// Tried are the formats tried, in order, with the error each of them gave.
+	Tried []KeyFormatAttempt
+}
+
+// KeyFormatAttempt is a private key format tried by ParsePrivateKeyPem.
+type KeyFormatAttempt struct {
+	Format string
+	Err    error
+}
+
+func (e *KeyFormatError) Error() string {
+	var msgs []string
+	for _, a := range e.Tried {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", a.Format, a.Err))
+	}
+	return "failed to parse private key, tried " + strings.Join(msgs, ", ")
+}
+
+func (e *KeyFormatError) Unwrap() []error {
+	var errs []error
+	for _, a := range e.Tried {
+		errs = append(errs, a.Err)
+	}
+	return errs
+}
+
+// ParsePrivateKeyPem parses the first PEM block in pemBytes as a private key without
+// looking at the block type. PKCS#8 is tried first, then PKCS#1 RSA and then SEC 1 EC,
+// the key of the first format that succeeds is returned. A *KeyFormatError lists the
+// formats tried if none of them succeeds.
+func ParsePrivateKeyPem(pemBytes []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	formats := []struct {
+		name  string
+		parse func([]byte) (interface{}, error)
+	}{
+		{"PKCS#8", x509.ParsePKCS8PrivateKey},
+		{"PKCS#1", func(der []byte) (interface{}, error) { return x509.ParsePKCS1PrivateKey(der) }},
+		{"SEC 1", func(der []byte) (interface{}, error) { return x509.ParseECPrivateKey(der) }},
+	}
+	formatErr := &KeyFormatError{}
+	for _, f := range formats {
+		k, err := f.parse(block.Bytes)
+		if err != nil {
+			formatErr.Tried = append(formatErr.Tried, KeyFormatAttempt{Format: f.name, Err: err})
+			continue
+		}
+		signer, ok := k.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type: %T", k)
+		}
+		return signer, nil
+	}
+	return nil, formatErr
+}
+
+// ParsePrivateKeyPEM is ParsePrivateKeyPem returning the key as an interface{}, kept
+// for callers written against it.
+func ParsePrivateKeyPEM(pemBytes []byte) (interface{}, error) {
+	signer, err := ParsePrivateKeyPem(pemBytes)
+	if err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+// WritePrivateKeyToPemFile writes key to fileName, PEM encoded as by EncodePrivateKeyPem.
+func WritePrivateKeyToPemFile(key interface{}, fileName string) error {
+	keyPem, err := EncodePrivateKeyPem(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode private key for %s: %v", fileName, err)
+	}
+	keyFile, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for writing private key: %v", fileName, err)
+	}
+	defer keyFile.Close()
+	if _, err := keyFile.Write(keyPem); err != nil {
+		return fmt.Errorf("failed to write private key to %s: %v", fileName, err)
 	}
+	logger.Printf("wrote private key %s to file", fileName)
+	return nil
 }
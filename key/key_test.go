@@ -1,8 +1,19 @@
 package key
 
 import (
+	"bytes"
 	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -20,3 +31,312 @@ func TestPublicECDSAKey(t *testing.T) {
 		t.Fatalf("got: %v, want %v", reflect.TypeOf(PublicKey(k)), reflect.TypeOf((*ecdsa.PublicKey)(nil)))
 	}
 }
+
+var pemRSAPublicKey = `-----BEGIN PUBLIC KEY-----
+MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA3VoPN9PKUjKFLMwOge6+
+wnDi8sbETGIx2FKXGgqtAKpzmem53kRGEQg8WeqRmp12wgp74TGpkEXsGae7RS1k
+enJCnma4fii+noGH7R0qKgHvPrI2Bwa9hzsH8tHxpyM3qrXslOmD45EH9SxIDUBJ
+FehNdaPbLP1gFyahKMsdfxFJLUvbUycuZSJ2ZnIgeVxwm4qbSvZInL9Iu4FzuPtg
+fINKcbbovy1qq4KvPIrXzhbY3PWDc6btxCf3SE0JdE1MCPThntB62/bLMSQ7xdDR
+FF53oIpvxe/SCOymfWq/LW849Ytv3Xwod0+wzAP8STXG4HSELS4UedPYeHJJJYcZ
++QIDAQAB
+-----END PUBLIC KEY-----
+`
+
+var pemECPublicKey = `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEteEAtPfe6N2NKwDdzd3gji+JPwMV
+4En0MXKhvEGJKwCs2jp1yuaXqcNzFxNd6PbMbrYi9aPbRKOIPtcRP2czJw==
+-----END PUBLIC KEY-----
+`
+
+func parsePublicKeyPem(t *testing.T, data string) interface{} {
+	block, _ := pem.Decode([]byte(data))
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse public key: %v", err)
+	}
+	return pub
+}
+
+// golden values from:
+// openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func TestSPKIPin(t *testing.T) {
+	tests := map[string]string{
+		pemRSAPublicKey: "4PV4Fx//yDJEPHBYjkiX0d+rVl+cr/KvA1PCNR0BYx0=",
+		pemECPublicKey:  "F/aKzCIDErwl62yRN5RjThe915UAsIYCE1ICg1QW+GI=",
+	}
+	for data, want := range tests {
+		pin, err := SPKIPin(parsePublicKeyPem(t, data))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if pin != want {
+			t.Fatalf("got: %v, want %v", pin, want)
+		}
+	}
+}
+
+func TestEncodePrivateKeyPem(t *testing.T) {
+	for _, k := range []interface{}{GenerateKey("RSA", 1024), GenerateKey("P256", 0)} {
+		data, err := EncodePrivateKeyPem(k)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		block, _ := pem.Decode(data)
+		if block == nil || (block.Type != "RSA PRIVATE KEY" && block.Type != "EC PRIVATE KEY") {
+			t.Fatalf("wrong PEM encoding: %s", data)
+		}
+	}
+	if _, err := EncodePrivateKeyPem("foo"); err == nil {
+		t.Fatal("expected error for unknown key type")
+	}
+}
+
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+func TestWritePrivateKeyToPemFile(t *testing.T) {
+	rec := &recordingLogger{}
+	SetLogger(rec)
+	defer SetLogger(nil)
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "key.pem")
+	k := GenerateKey("P256", 0)
+	if err := WritePrivateKeyToPemFile(k, fileName); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("failed to read the key file: %v", err)
+	}
+	parsed, err := ParsePrivateKeyPem(data)
+	if err != nil || !reflect.DeepEqual(parsed.Public(), PublicKey(k)) {
+		t.Fatalf("got %v, %v, want the written key", parsed, err)
+	}
+	if len(rec.messages) != 1 || rec.messages[0] != "wrote private key "+fileName+" to file" {
+		t.Fatalf("got messages %q", rec.messages)
+	}
+
+	unknown := filepath.Join(dir, "unknown.pem")
+	if err := WritePrivateKeyToPemFile("foo", unknown); err == nil {
+		t.Fatal("expected error for unknown key type")
+	}
+	if _, err := os.Stat(unknown); !os.IsNotExist(err) {
+		t.Fatalf("got %v, want no file for an unknown key type", err)
+	}
+	if err := WritePrivateKeyToPemFile(k, filepath.Join(dir, "missing", "key.pem")); err == nil {
+		t.Fatal("expected error for a missing directory")
+	}
+	if PublicKey("foo") != nil {
+		t.Fatal("expected no public key for an unknown key type")
+	}
+	if len(rec.messages) != 2 {
+		t.Fatalf("got messages %q, want the unknown key type logged", rec.messages)
+	}
+}
+
+func TestGenerateKeyPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for an unknown key type")
+		}
+	}()
+	GenerateKey("DSA", 0)
+}
+
+func TestGenerate(t *testing.T) {
+	priv, err := Generate(KeySpec{Type: "P-384"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ec, ok := priv.(*ecdsa.PrivateKey); !ok || ec.Curve != elliptic.P384() {
+		t.Fatalf("got: %T, want a P-384 key", priv)
+	}
+	if _, err := Generate(KeySpec{Type: "RSA", Bits: 1024}); err == nil {
+		t.Fatal("expected error for a 1024 bit RSA key")
+	}
+	priv, err = Generate(KeySpec{Type: "RSA", Bits: 1024, AllowWeak: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rsaKey, ok := priv.(*rsa.PrivateKey); !ok || rsaKey.N.BitLen() != 1024 {
+		t.Fatalf("got: %T, want a 1024 bit RSA key", priv)
+	}
+	if _, err := Generate(KeySpec{Type: "P-192"}); err == nil {
+		t.Fatal("expected error for an unknown curve")
+	}
+}
+
+func TestGenerateRSA(t *testing.T) {
+	if _, err := GenerateRSA(1024); err == nil {
+		t.Fatal("expected error for a 1024 bit RSA key")
+	}
+}
+
+func TestGenerateECDSA(t *testing.T) {
+	for name, curve := range map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()} {
+		priv, err := GenerateECDSA(name)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if priv.Curve != curve {
+			t.Fatalf("%s: got curve %v", name, priv.Curve.Params().Name)
+		}
+	}
+}
+
+// The expected values are the Subject Key Identifier printed by openssl x509 -text.
+func TestPublicKeyBitArrayMatchesOpensslSKID(t *testing.T) {
+	tests := map[string]string{
+		opensslRSACertPem:     "ee209b0ecf158b8bf829225cd6dbf424667444ce",
+		opensslECDSACertPem:   "9fe813686c9f411bfc5a9599186ba5b65f4f97e2",
+		opensslEd25519CertPem: "57199a36f3182a40e49ea27cf12a0910caebc248",
+	}
+	for certPem, want := range tests {
+		block, _ := pem.Decode([]byte(certPem))
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatalf("failed to parse certificate: %v", err)
+		}
+		bits, err := PublicKeyBitArray(cert.PublicKey)
+		if err != nil {
+			t.Fatalf("%T: unexpected error: %v", cert.PublicKey, err)
+		}
+		sum := sha1.Sum(bits)
+		if got := hex.EncodeToString(sum[:]); got != want {
+			t.Fatalf("%T: got: %s, want %s", cert.PublicKey, got, want)
+		}
+	}
+}
+
+var opensslRSACertPem = `-----BEGIN CERTIFICATE-----
+MIICPjCCAaegAwIBAgIUetYeTcmNtoZf9Kg4oIc+gRAqxVIwDQYJKoZIhvcNAQEL
+BQAwMTETMBEGA1UEAwwKd3d3LmZvby5zZTENMAsGA1UECgwEdGVzdDELMAkGA1UE
+BhMCU0UwHhcNMjYxMDE2MDgwOTA5WhcNMzYxMDEzMDgwOTA5WjAxMRMwEQYDVQQD
+DAp3d3cuZm9vLnNlMQ0wCwYDVQQKDAR0ZXN0MQswCQYDVQQGEwJTRTCBnzANBgkq
+hkiG9w0BAQEFAAOBjQAwgYkCgYEA2JQsXsycTNJjwTE5cc6C7TW8iJ5/a/5vswGl
+RHpaaUQvg+RxwN5EKKo1qa8j529jgaSLj4CrOo1tm9nRDNHqlt7UKQUTfBgw/Fnz
+ddQ0BmcSu0Iptb5Pa0oB0giVX+AXP356OTXkD0LwS15GIXuPyIr5vDsK/8EjfxPe
+MLQiB6kCAwEAAaNTMFEwHQYDVR0OBBYEFO4gmw7PFYuL+CkiXNbb9CRmdETOMB8G
+A1UdIwQYMBaAFO4gmw7PFYuL+CkiXNbb9CRmdETOMA8GA1UdEwEB/wQFMAMBAf8w
+DQYJKoZIhvcNAQELBQADgYEApFw161Xv/Pl3YGbjEgLg47zuRjKX1RICIVO40bdF
+k8Ulyh+cmCotALhy0VzoXdElAsRzJWgM97V6FtWV4JNsZFj6ULxVhv06wBDK9urN
+5b9EkPZf//VhNKw5UmZvEyFEwrdWfO7Ng+H4S71j2H+vPt6xsLJ9l/NNok7ao9sK
+4/w=
+-----END CERTIFICATE-----`
+
+var opensslECDSACertPem = `-----BEGIN CERTIFICATE-----
+MIIBuDCCAV2gAwIBAgIUGO4plB6nZpOa8Xt3l8MXpVTrjiYwCgYIKoZIzj0EAwIw
+MTELMAkGA1UEBhMCU0UxDTALBgNVBAoMBHRlc3QxEzARBgNVBAMMCnd3dy5mb28u
+c2UwHhcNMjYxMDE2MDc1NjQzWhcNMzYxMDEzMDc1NjQzWjAxMQswCQYDVQQGEwJT
+RTENMAsGA1UECgwEdGVzdDETMBEGA1UEAwwKd3d3LmZvby5zZTBZMBMGByqGSM49
+AgEGCCqGSM49AwEHA0IABLXhALT33ujdjSsA3c3d4I4viT8DFeBJ9DFyobxBiSsA
+rNo6dcrml6nDcxcTXej2zG62IvWj20SjiD7XET9nMyejUzBRMB0GA1UdDgQWBBSf
+6BNobJ9BG/xalZkYa6W2X0+X4jAfBgNVHSMEGDAWgBSf6BNobJ9BG/xalZkYa6W2
+X0+X4jAPBgNVHRMBAf8EBTADAQH/MAoGCCqGSM49BAMCA0kAMEYCIQCVmq+Wep4+
+EZhqqMWaeQlX+Vhm/y8edsl0MreAj6iwTAIhAKgxymA9sQR0DaIQ5tfb9+58kqJt
+U2a5yqn6Crb7MbsE
+-----END CERTIFICATE-----
+`
+
+var opensslEd25519CertPem = `-----BEGIN CERTIFICATE-----
+MIIBdzCCASmgAwIBAgIUYIXGMONXuoAyRSfabZkwzU86gtMwBQYDK2VwMDExEzAR
+BgNVBAMMCnd3dy5mb28uc2UxDTALBgNVBAoMBHRlc3QxCzAJBgNVBAYTAlNFMB4X
+DTI2MTAxNjA4MTEyMVoXDTM2MTAxMzA4MTEyMVowMTETMBEGA1UEAwwKd3d3LmZv
+by5zZTENMAsGA1UECgwEdGVzdDELMAkGA1UEBhMCU0UwKjAFBgMrZXADIQC1HrxF
+/LfWlEPHsyhBwDiIZY71asBZZaTAvE9WvrAWI6NTMFEwHQYDVR0OBBYEFFcZmjbz
+GCpA5J6ifPEqCRDK68JIMB8GA1UdIwQYMBaAFFcZmjbzGCpA5J6ifPEqCRDK68JI
+MA8GA1UdEwEB/wQFMAMBAf8wBQYDK2VwA0EAM1LCn2J+yhwpyMLwroSZ+2rUXP3G
+uEU1ReiYONBoUvuveZBtGugI154/f9Pe4Oj3/uF53IbTDmvR3bFHj+CPBA==
+-----END CERTIFICATE-----`
+
+func TestParsePrivateKeyPem(t *testing.T) {
+	rsaKey := GenerateKey("RSA", 1024).(*rsa.PrivateKey)
+	ecKey := GenerateKey("P256", 0).(*ecdsa.PrivateKey)
+	_, edKey, _ := ed25519.GenerateKey(RandReader)
+	pkcs8 := func(k interface{}) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			t.Fatalf("%T: failed to marshal: %v", k, err)
+		}
+		return der
+	}
+	sec1, _ := x509.MarshalECPrivateKey(ecKey)
+	tests := []struct {
+		name string
+		key  interface{}
+		der  []byte
+	}{
+		{"PKCS#8 RSA", rsaKey, pkcs8(rsaKey)},
+		{"PKCS#8 EC", ecKey, pkcs8(ecKey)},
+		{"PKCS#8 Ed25519", edKey, pkcs8(edKey)},
+		{"PKCS#1", rsaKey, x509.MarshalPKCS1PrivateKey(rsaKey)},
+		{"SEC 1", ecKey, sec1},
+	}
+	for _, tt := range tests {
+		// the block type is deliberately wrong, only the content decides the format
+		keyPem := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: tt.der})
+		parsed, err := ParsePrivateKeyPem(keyPem)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(parsed.Public(), PublicKey(tt.key)) {
+			t.Fatalf("%s: parsed key differs", tt.name)
+		}
+	}
+	_, err := ParsePrivateKeyPem([]byte(pemRSAPublicKey))
+	var formatErr *KeyFormatError
+	if !errors.As(err, &formatErr) {
+		t.Fatalf("got: %v, want a KeyFormatError", err)
+	}
+	if len(formatErr.Tried) != 3 || formatErr.Tried[0].Format != "PKCS#8" || formatErr.Tried[1].Format != "PKCS#1" || formatErr.Tried[2].Format != "SEC 1" {
+		t.Fatalf("got: %+v, want PKCS#8, PKCS#1 and SEC 1 tried", formatErr.Tried)
+	}
+}
+
+// generated with openssl genpkey -algorithm ed25519 | openssl pkey -pubout
+var pemEd25519PublicKey = `-----BEGIN PUBLIC KEY-----
+MCowBQYDK2VwAyEAEQygISJa64a+XKD7girXc0EBGxLWzb+XgLUIewziQ/4=
+-----END PUBLIC KEY-----
+`
+
+func TestMarshalPublicPem(t *testing.T) {
+	for _, want := range []string{pemRSAPublicKey, pemECPublicKey, pemEd25519PublicKey} {
+		pub := parsePublicKeyPem(t, want)
+		got, err := MarshalPublicPem(pub)
+		if err != nil {
+			t.Fatalf("%T: unexpected error: %v", pub, err)
+		}
+		if string(got) != want {
+			t.Fatalf("%T: got:\n%s\nwant openssl output:\n%s", pub, got, want)
+		}
+		var buf bytes.Buffer
+		if err := WritePublicPem(&buf, pub); err != nil || buf.String() != want {
+			t.Fatalf("%T: WritePublicPem wrote %q, %v", pub, buf.String(), err)
+		}
+	}
+	if _, err := MarshalPublicPem("not a key"); err == nil {
+		t.Fatal("expected error for an unknown key type")
+	}
+}
+
+func TestParsePrivateKeyPEM(t *testing.T) {
+	ecKey := GenerateKey("P256", 0).(*ecdsa.PrivateKey)
+	sec1, _ := x509.MarshalECPrivateKey(ecKey)
+	parsed, err := ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: sec1}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(PublicKey(parsed), PublicKey(ecKey)) {
+		t.Fatal("parsed key differs")
+	}
+	// a nil signer must not become a non-nil interface{}
+	if parsed, err := ParsePrivateKeyPEM([]byte(pemRSAPublicKey)); err == nil || parsed != nil {
+		t.Fatalf("got: %v, %v, want a nil key and an error", parsed, err)
+	}
+}
@@ -0,0 +1,23 @@
+package key
+
+// Logger receives the messages of the package, such as the files written by
+// WritePrivateKeyToPemFile. A *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// nopLogger drops all messages, the package is silent until SetLogger is called.
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...interface{}) {}
+
+var logger Logger = nopLogger{}
+
+// SetLogger sets where the package writes its messages, nil silences it again.
+// Like RandReader it must only be changed before keys are created.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = nopLogger{}
+	}
+	logger = l
+}
@@ -0,0 +1,35 @@
+package key
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ToOpenSSH returns pub, an RSA, ECDSA or Ed25519 public key, as a line for an
+// authorized_keys file, such as "ssh-ed25519 AAAA... comment". The comment is optional.
+func ToOpenSSH(pub interface{}, comment string) (string, error) {
+	switch pub.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+	default:
+		return "", fmt.Errorf("unsupported public key type for SSH: %T", pub)
+	}
+	sshKey, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	line := strings.TrimSuffix(string(ssh.MarshalAuthorizedKey(sshKey)), "\n")
+	if comment != "" {
+		line += " " + comment
+	}
+	return line, nil
+}
+
+// PublicKeyToSSH returns pub as an authorized_keys line without comment, see ToOpenSSH.
+func PublicKeyToSSH(pub interface{}) (string, error) {
+	return ToOpenSSH(pub, "")
+}
@@ -0,0 +1,70 @@
+package key
+
+import (
+	"bytes"
+	"crypto/dsa"
+	"crypto/ed25519"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestToOpenSSH(t *testing.T) {
+	_, edPriv, _ := ed25519.GenerateKey(RandReader)
+	tests := map[string]interface{}{
+		"ssh-rsa":             PublicKey(GenerateKey("RSA", 1024)),
+		"ecdsa-sha2-nistp256": PublicKey(GenerateKey("P256", 0)),
+		"ecdsa-sha2-nistp384": PublicKey(GenerateKey("P384", 0)),
+		"ssh-ed25519":         edPriv.Public(),
+	}
+	for keyType, pub := range tests {
+		line, err := ToOpenSSH(pub, "root@www.foo.se")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", keyType, err)
+		}
+		if !strings.HasPrefix(line, keyType+" ") {
+			t.Fatalf("%s: got %q", keyType, line)
+		}
+		parsed, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			t.Fatalf("%s: failed to parse %q: %v", keyType, line, err)
+		}
+		if comment != "root@www.foo.se" {
+			t.Fatalf("%s: got comment %q", keyType, comment)
+		}
+		want, _ := ssh.NewPublicKey(pub)
+		if !bytes.Equal(parsed.Marshal(), want.Marshal()) {
+			t.Fatalf("%s: key material differs", keyType)
+		}
+	}
+	if line, _ := ToOpenSSH(tests["ssh-ed25519"], ""); strings.Count(line, " ") != 1 {
+		t.Fatalf("got %q, want no comment", line)
+	}
+	if _, err := ToOpenSSH("not a key", ""); err == nil {
+		t.Fatal("expected error for an unknown key type")
+	}
+}
+
+func TestPublicKeyToSSH(t *testing.T) {
+	// generated with ssh-keygen -i -m PKCS8
+	tests := map[string]string{
+		pemRSAPublicKey: "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQDdWg8308pSMoUszA6B7r7CcOLyxsRMYjHYUpcaCq0AqnOZ6bneREYRCDxZ6pGanXbCCnvhMamQRewZp7tFLWR6ckKeZrh+KL6egYftHSoqAe8+sjYHBr2HOwfy0fGnIzeqteyU6YPjkQf1LEgNQEkV6E11o9ss/WAXJqEoyx1/EUktS9tTJy5lInZmciB5XHCbiptK9kicv0i7gXO4+2B8g0pxtui/LWqrgq88itfOFtjc9YNzpu3EJ/dITQl0TUwI9OGe0Hrb9ssxJDvF0NEUXnegim/F79II7KZ9ar8tbzj1i2/dfCh3T7DMA/xJNcbgdIQtLhR509h4ckklhxn5",
+		pemECPublicKey:  "ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBLXhALT33ujdjSsA3c3d4I4viT8DFeBJ9DFyobxBiSsArNo6dcrml6nDcxcTXej2zG62IvWj20SjiD7XET9nMyc=",
+	}
+	for pemKey, want := range tests {
+		got, err := PublicKeyToSSH(parsePublicKeyPem(t, pemKey))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want {
+			t.Fatalf("got: %s, want ssh-keygen output: %s", got, want)
+		}
+	}
+	if line, err := PublicKeyToSSH(parsePublicKeyPem(t, pemEd25519PublicKey)); err != nil || !strings.HasPrefix(line, "ssh-ed25519 ") {
+		t.Fatalf("got: %q %v, want an ssh-ed25519 line", line, err)
+	}
+	if _, err := PublicKeyToSSH(&dsa.PublicKey{}); err == nil {
+		t.Fatal("expected error for a DSA key")
+	}
+}
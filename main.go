@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/chrjoh/certificateBar/certificatebar"
+	"github.com/ignalina/certificateBar/v2/certificatebar"
 )
 
 var (